@@ -0,0 +1,217 @@
+package actions
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// action names, the display runs the ui actions itself
+const (
+	Play       = "play"
+	Pause      = "pause"
+	Stop       = "stop"
+	Toggle     = "toggle" // play or pause depending on the state
+	Next       = "next"
+	Prev       = "prev"
+	Mute       = "mute" // [on|off|toggle], default on
+	UnMute     = "unmute"
+	Volume     = "volume" // N, +N or -N
+	Seek       = "seek"   // +10s, -1m or an absolute position like 1:30
+	Random     = "random" // [on|off|toggle], default toggle
+	Repeat     = "repeat" // [off|all|single|cycle], default cycle
+	Preset     = "preset" // recall slot N
+	SavePreset = "save-preset"
+	Favourite  = "favourite" // add the current item to the favourites
+	Favourites = "favourites"
+	Playlist   = "playlist" // play the named playlist
+
+	Quit     = "quit"
+	View     = "view" // name or number of a view, or next
+	Search   = "search"
+	JumpTo   = "jump"
+	NextView = "next-view"
+	Theme    = "theme" // name of a theme, or next (default)
+)
+
+var ErrUnknownAction = errors.New("unknown action")
+
+// Action is a parsed action like "volume +5" or "seek -10s", arguments
+// are validated on parsing
+type Action struct {
+	Name     string
+	Arg      string
+	Number   int           // volume, preset slot or view number
+	Position time.Duration // seek target or offset
+	Relative bool          // volume and seek change the current value
+}
+
+func Parse(s string) (Action, error) {
+	fields := strings.Fields(s)
+	if len(fields) == 0 {
+		return Action{}, errors.New("actions: empty action")
+	}
+	a := Action{Name: fields[0], Arg: strings.Join(fields[1:], " ")}
+	if err := a.parseArg(); err != nil {
+		return Action{}, fmt.Errorf("actions: %q: %w", s, err)
+	}
+	return a, nil
+}
+
+// MustParse is Parse for built in actions, it panics on errors
+func MustParse(s string) Action {
+	a, err := Parse(s)
+	if err != nil {
+		panic(err)
+	}
+	return a
+}
+
+func (a *Action) parseArg() error {
+	switch a.Name {
+	case Play, Pause, Stop, Toggle, Next, Prev, UnMute, Favourite, Favourites, Quit, Search, JumpTo, NextView:
+		return a.noArg()
+	case Mute, Random:
+		return a.oneOf("on", "off", "toggle")
+	case Repeat:
+		return a.oneOf("off", "all", "single", "cycle")
+	case Volume:
+		return a.parseVolume()
+	case Seek:
+		return a.parseSeek()
+	case Preset:
+		return a.parseSlot()
+	case SavePreset:
+		// without a slot the display asks for it
+		if a.Arg == "" {
+			return nil
+		}
+		return a.parseSlot()
+	case Playlist:
+		if a.Arg == "" {
+			return errors.New("missing playlist name")
+		}
+		return nil
+	case Theme:
+		// the display knows the themes
+		return nil
+	case View:
+		if a.Arg == "" {
+			return errors.New("missing view")
+		}
+		if n, err := strconv.Atoi(a.Arg); err == nil {
+			a.Number = n
+		}
+		return nil
+	default:
+		return ErrUnknownAction
+	}
+}
+
+func (a *Action) noArg() error {
+	if a.Arg != "" {
+		return fmt.Errorf("unexpected argument %q", a.Arg)
+	}
+	return nil
+}
+
+func (a *Action) oneOf(values ...string) error {
+	if a.Arg == "" {
+		return nil
+	}
+	for _, value := range values {
+		if a.Arg == value {
+			return nil
+		}
+	}
+	return fmt.Errorf("argument %q is not one of %s", a.Arg, strings.Join(values, ", "))
+}
+
+func (a *Action) parseVolume() error {
+	a.Relative = strings.HasPrefix(a.Arg, "+") || strings.HasPrefix(a.Arg, "-")
+	volume, err := strconv.Atoi(a.Arg)
+	if err != nil {
+		return fmt.Errorf("invalid volume %q", a.Arg)
+	}
+	if !a.Relative && (volume < 0 || volume > 100) {
+		return fmt.Errorf("volume %d out of range 0-100", volume)
+	}
+	a.Number = volume
+	return nil
+}
+
+func (a *Action) parseSeek() error {
+	if strings.HasPrefix(a.Arg, "+") || strings.HasPrefix(a.Arg, "-") {
+		offset, err := time.ParseDuration(a.Arg)
+		if err != nil {
+			return fmt.Errorf("invalid offset %q", a.Arg)
+		}
+		a.Position, a.Relative = offset, true
+		return nil
+	}
+	position, err := ParsePosition(a.Arg)
+	if err != nil {
+		return err
+	}
+	a.Position = position
+	return nil
+}
+
+func (a *Action) parseSlot() error {
+	slot, err := strconv.Atoi(a.Arg)
+	if err != nil || slot < 1 {
+		return fmt.Errorf("invalid preset %q", a.Arg)
+	}
+	a.Number = slot
+	return nil
+}
+
+func (a Action) String() string {
+	if a.Arg == "" {
+		return a.Name
+	}
+	return a.Name + " " + a.Arg
+}
+
+// UI reports whether the display has to run the action
+func (a Action) UI() bool {
+	switch a.Name {
+	case Quit, View, Search, JumpTo, NextView, Theme:
+		return true
+	case SavePreset:
+		return a.Arg == ""
+	}
+	return false
+}
+
+func (a *Action) UnmarshalText(text []byte) error {
+	parsed, err := Parse(string(text))
+	if err != nil {
+		return err
+	}
+	*a = parsed
+	return nil
+}
+
+func (a Action) MarshalText() ([]byte, error) {
+	return []byte(a.String()), nil
+}
+
+// ParsePosition parses a position like "90", "1:30" or "1:02:03"
+func ParsePosition(s string) (time.Duration, error) {
+	parts := strings.Split(strings.TrimSpace(s), ":")
+	if len(parts) > 3 {
+		return 0, errors.New("too many fields in position")
+	}
+	var seconds int
+	for _, part := range parts {
+		value, err := strconv.Atoi(part)
+		if err != nil || value < 0 {
+			return 0, fmt.Errorf("invalid position: %q", s)
+		}
+		seconds = seconds*60 + value
+	}
+	return time.Duration(seconds) * time.Second, nil
+}
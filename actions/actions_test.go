@@ -0,0 +1,115 @@
+package actions
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log"
+	"reflect"
+	"strconv"
+	"testing"
+	"time"
+
+	"volumgui/client"
+)
+
+func Test_Parse(t *testing.T) {
+	tests := []struct {
+		in   string
+		want Action
+	}{
+		{"play", Action{Name: Play}},
+		{"volume +5", Action{Name: Volume, Arg: "+5", Number: 5, Relative: true}},
+		{"volume -5", Action{Name: Volume, Arg: "-5", Number: -5, Relative: true}},
+		{"volume 40", Action{Name: Volume, Arg: "40", Number: 40}},
+		{"seek -10s", Action{Name: Seek, Arg: "-10s", Position: -10 * time.Second, Relative: true}},
+		{"seek 1:30", Action{Name: Seek, Arg: "1:30", Position: 90 * time.Second}},
+		{"preset 3", Action{Name: Preset, Arg: "3", Number: 3}},
+		{"save-preset", Action{Name: SavePreset}},
+		{"playlist  morning radio", Action{Name: Playlist, Arg: "morning radio"}},
+		{"view queue", Action{Name: View, Arg: "queue"}},
+		{"view 2", Action{Name: View, Arg: "2", Number: 2}},
+		{"theme", Action{Name: Theme}},
+		{"theme solarized", Action{Name: Theme, Arg: "solarized"}},
+	}
+	for _, test := range tests {
+		got, err := Parse(test.in)
+		if err != nil {
+			t.Errorf("%q: %v", test.in, err)
+			continue
+		}
+		if !reflect.DeepEqual(got, test.want) {
+			t.Errorf("%q: expected %+v, got %+v", test.in, test.want, got)
+		}
+	}
+
+	for _, in := range []string{"", "dance", "play now", "volume 150", "volume loud", "seek 1:xx", "preset 0", "random maybe", "view"} {
+		if _, err := Parse(in); err == nil {
+			t.Errorf("%q: expected an error", in)
+		}
+	}
+	if _, err := Parse("dance"); !errors.Is(err, ErrUnknownAction) {
+		t.Errorf("expected ErrUnknownAction, got %v", err)
+	}
+}
+
+// client recording the calls made by the runner
+type recordingClient struct {
+	client.ClientInterface
+	calls []string
+}
+
+func (c *recordingClient) record(call string) error {
+	c.calls = append(c.calls, call)
+	return nil
+}
+
+func (c *recordingClient) Play(ctx context.Context) error  { return c.record("play") }
+func (c *recordingClient) Pause(ctx context.Context) error { return c.record("pause") }
+func (c *recordingClient) SetVolume(ctx context.Context, volume int, mute bool) error {
+	return c.record("volume " + strconv.Itoa(volume))
+}
+func (c *recordingClient) SetRepeat(ctx context.Context, repeat bool) error {
+	if repeat {
+		return c.record("repeat all")
+	}
+	return c.record("repeat off")
+}
+func (c *recordingClient) SetRepeatSingle(ctx context.Context, single bool) error {
+	return c.record("repeat single")
+}
+
+func Test_Runner(t *testing.T) {
+	c := recordingClient{}
+	state := client.State{Status: "play", Volume: 98, Repeat: true}
+	logger := log.New(io.Discard, "", 0)
+	r := NewRunner(&c, func() client.State { return state }, nil, logger, logger)
+	ctx := context.Background()
+
+	for _, action := range []string{"toggle", "volume +5", "volume -10", "repeat"} {
+		if err := r.Run(ctx, MustParse(action)); err != nil {
+			t.Fatalf("%s: %v", action, err)
+		}
+	}
+	want := []string{"pause", "volume 100", "volume 88", "repeat single"}
+	if !reflect.DeepEqual(c.calls, want) {
+		t.Fatalf("expected %v, got %v", want, c.calls)
+	}
+
+	errs := make(chan error, 1)
+	r.ErrChan = errs
+	if err := r.Run(ctx, MustParse("preset 1")); !errors.Is(err, client.ErrUnsupported) {
+		t.Fatalf("expected ErrUnsupported without presets, got %v", err)
+	}
+	if err := <-errs; !errors.Is(err, client.ErrUnsupported) {
+		t.Fatalf("failure not reported, got %v", err)
+	}
+
+	// ui actions are passed on to the display
+	if err := r.Run(ctx, MustParse("view queue")); err != nil {
+		t.Fatal(err)
+	}
+	if a := <-r.UIChan; a.Name != View || a.Arg != "queue" {
+		t.Fatalf("unexpected ui action %+v", a)
+	}
+}
@@ -0,0 +1,171 @@
+package actions
+
+import (
+	"context"
+	"fmt"
+	"log"
+
+	"volumgui/client"
+	"volumgui/presets"
+)
+
+// Runner executes the client actions, ui actions are passed on to UIChan
+type Runner struct {
+	Client   client.ClientInterface
+	State    func() client.State // current state of the client
+	Presets  *presets.Presets    // nil without preset support
+	UIChan   chan Action
+	ErrChan  chan<- error // failed actions are sent here when set, dropped if nobody is ready
+	InfoLog  *log.Logger
+	ErrorLog *log.Logger
+}
+
+func NewRunner(c client.ClientInterface, state func() client.State, p *presets.Presets, info_log *log.Logger, error_log *log.Logger) *Runner {
+	return &Runner{
+		Client:   c,
+		State:    state,
+		Presets:  p,
+		UIChan:   make(chan Action, 1),
+		InfoLog:  info_log,
+		ErrorLog: error_log,
+	}
+}
+
+func (r *Runner) Run(ctx context.Context, a Action) error {
+	err := r.run(ctx, a)
+	if err != nil && r.ErrChan != nil {
+		select {
+		case r.ErrChan <- err:
+		default:
+		}
+	}
+	return err
+}
+
+func (r *Runner) run(ctx context.Context, a Action) error {
+	if a.UI() {
+		select {
+		case r.UIChan <- a:
+			return nil
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+
+	c := r.Client
+	switch a.Name {
+	case Play:
+		return c.Play(ctx)
+	case Pause:
+		return c.Pause(ctx)
+	case Stop:
+		return c.Stop(ctx)
+	case Toggle:
+		if r.State().Status == "play" {
+			return c.Pause(ctx)
+		}
+		return c.Play(ctx)
+	case Next:
+		return c.Next(ctx)
+	case Prev:
+		return c.Prev(ctx)
+	case Mute:
+		if a.Arg == "off" || (a.Arg == "toggle" && r.State().Mute) {
+			return c.UnMute(ctx)
+		}
+		return c.Mute(ctx)
+	case UnMute:
+		return c.UnMute(ctx)
+	case Volume:
+		return c.SetVolume(ctx, r.volume(a), false)
+	case Seek:
+		if a.Relative {
+			return c.SeekBy(ctx, a.Position)
+		}
+		return c.Seek(ctx, a.Position)
+	case Random:
+		return c.SetRandom(ctx, r.random(a))
+	case Repeat:
+		return r.repeat(ctx, a)
+	case Preset:
+		if r.Presets == nil {
+			return fmt.Errorf("%s: %w", a, client.ErrUnsupported)
+		}
+		_, err := r.Presets.Recall(ctx, a.Number)
+		return err
+	case SavePreset:
+		if r.Presets == nil {
+			return fmt.Errorf("%s: %w", a, client.ErrUnsupported)
+		}
+		_, err := r.Presets.Save(a.Number)
+		return err
+	case Favourite, Favourites, Playlist:
+		return r.playlist(ctx, a)
+	}
+	return fmt.Errorf("%s: %w", a, ErrUnknownAction)
+}
+
+// volume for absolute and relative changes, clamped to 0-100
+func (r *Runner) volume(a Action) int {
+	volume := a.Number
+	if a.Relative {
+		volume += r.State().Volume
+	}
+	if volume < 0 {
+		return 0
+	}
+	if volume > 100 {
+		return 100
+	}
+	return volume
+}
+
+func (r *Runner) random(a Action) bool {
+	switch a.Arg {
+	case "on":
+		return true
+	case "off":
+		return false
+	default:
+		return !r.State().Random
+	}
+}
+
+// cycle through off, all and single
+func (r *Runner) repeat(ctx context.Context, a Action) error {
+	mode := a.Arg
+	if mode == "" || mode == "cycle" {
+		state := r.State()
+		switch {
+		case state.RepeatSingle:
+			mode = "off"
+		case state.Repeat:
+			mode = "single"
+		default:
+			mode = "all"
+		}
+	}
+	switch mode {
+	case "single":
+		return r.Client.SetRepeatSingle(ctx, true)
+	case "all":
+		return r.Client.SetRepeat(ctx, true)
+	default:
+		return r.Client.SetRepeat(ctx, false)
+	}
+}
+
+func (r *Runner) playlist(ctx context.Context, a Action) error {
+	p, ok := r.Client.(client.PlaylistInterface)
+	if !ok {
+		return fmt.Errorf("%s: %w", a, client.ErrUnsupported)
+	}
+	switch a.Name {
+	case Favourite:
+		return p.AddToFavourites(ctx, r.State().QueueItem())
+	case Favourites:
+		return p.PlayFavourites(ctx)
+	default:
+		return p.PlayPlaylist(ctx, a.Arg)
+	}
+}
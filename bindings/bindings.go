@@ -0,0 +1,241 @@
+package bindings
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"volumgui/actions"
+	"volumgui/multiplexer"
+
+	"gopkg.in/yaml.v3"
+)
+
+const DefaultLircSocket = "/var/run/lirc/lircd"
+
+// Config maps inputs to actions, keys extend and override DefaultKeys
+type Config struct {
+	Keys        map[string]*actions.Action `yaml:"keys"` // a null action unbinds a default key
+	Multiplexer *Multiplexer               `yaml:"multiplexer"`
+	Buttons     []Button                   `yaml:"buttons"`
+	Chords      []Chord                    `yaml:"chords"`
+	Encoders    []Encoder                  `yaml:"encoders"`
+	Lirc        *Lirc                      `yaml:"lirc"`
+}
+
+// shift registers the buttons and encoders are wired to
+type Multiplexer struct {
+	Spi         int           `yaml:"spi"`         // 0 for SPI0
+	ChipSelect  int           `yaml:"chip_select"` // 0 for CE0
+	LoadPin     int           `yaml:"load_pin"`    // bcm number of the SH/LD pin
+	Chips       int           `yaml:"chips"`
+	ReadsPerSec int           `yaml:"reads_per_sec"`
+	Debounce    time.Duration `yaml:"debounce"`
+	ActiveLow   bool          `yaml:"active_low"`
+}
+
+// gestures of a button, zero thresholds use the defaults of the
+// multiplexer package
+type Button struct {
+	Button       int             `yaml:"button"`
+	Hold         time.Duration   `yaml:"hold"`          // long press threshold
+	RepeatEvery  time.Duration   `yaml:"repeat_every"`  // repeats while held after a long press
+	DoubleWithin time.Duration   `yaml:"double_within"` // enables double presses
+	Short        *actions.Action `yaml:"short"`
+	Long         *actions.Action `yaml:"long"`
+	Repeat       *actions.Action `yaml:"repeat"`
+	Double       *actions.Action `yaml:"double"`
+}
+
+type Chord struct {
+	Buttons []int          `yaml:"buttons"`
+	Action  actions.Action `yaml:"action"`
+}
+
+// encoder turns either change the volume or run an action per detent,
+// the push switch is bound like any other button
+type Encoder struct {
+	A              int             `yaml:"a"`
+	B              int             `yaml:"b"`
+	StepsPerDetent int             `yaml:"steps_per_detent"`
+	VolumeStep     int             `yaml:"volume_step"`
+	Clockwise      *actions.Action `yaml:"cw"`
+	CounterClock   *actions.Action `yaml:"ccw"`
+}
+
+// lircd socket and the actions of the button names it sends
+type Lirc struct {
+	Socket  string                    `yaml:"socket"`
+	Repeat  bool                      `yaml:"repeat"` // run actions again while a remote button is held
+	Buttons map[string]actions.Action `yaml:"buttons"`
+}
+
+// DefaultPath is bindings.yaml in the user config directory
+func DefaultPath() string {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "bindings.yaml"
+	}
+	return filepath.Join(dir, "volumgui", "bindings.yaml")
+}
+
+// Load reads the bindings at path, a missing file gives the default
+// key bindings only
+func Load(path string) (Config, error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return Config{}, nil
+	}
+	if err != nil {
+		return Config{}, fmt.Errorf("bindings: %w", err)
+	}
+	return Parse(data)
+}
+
+func Parse(data []byte) (Config, error) {
+	var config Config
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	decoder.KnownFields(true)
+	if err := decoder.Decode(&config); err != nil && !errors.Is(err, io.EOF) {
+		return Config{}, fmt.Errorf("bindings: %w", err)
+	}
+	if err := config.validate(); err != nil {
+		return Config{}, fmt.Errorf("bindings: %w", err)
+	}
+	return config, nil
+}
+
+func (c Config) validate() error {
+	if m := c.Multiplexer; m != nil {
+		if m.Chips < 1 {
+			return errors.New("multiplexer: chips must be at least 1")
+		}
+		if m.ReadsPerSec < 1 {
+			return errors.New("multiplexer: reads_per_sec must be positive")
+		}
+	} else if len(c.Buttons) > 0 || len(c.Chords) > 0 || len(c.Encoders) > 0 {
+		return errors.New("buttons, chords and encoders need a multiplexer")
+	}
+	for _, chord := range c.Chords {
+		if len(chord.Buttons) < 2 {
+			return fmt.Errorf("chord %v needs at least two buttons", chord.Buttons)
+		}
+	}
+	for i, e := range c.Encoders {
+		if e.VolumeStep == 0 && (e.Clockwise == nil || e.CounterClock == nil) {
+			return fmt.Errorf("encoder %d needs volume_step or both cw and ccw", i)
+		}
+	}
+	return nil
+}
+
+// DefaultKeys are the keyboard bindings of the display
+func DefaultKeys() map[string]actions.Action {
+	keys := map[string]actions.Action{
+		"q":       actions.MustParse("quit"),
+		"<C-c>":   actions.MustParse("quit"),
+		"<Tab>":   actions.MustParse("next-view"),
+		"/":       actions.MustParse("search"),
+		"<Left>":  actions.MustParse("seek -10s"),
+		"<Right>": actions.MustParse("seek +10s"),
+		"[":       actions.MustParse("seek -60s"),
+		"]":       actions.MustParse("seek +60s"),
+		"g":       actions.MustParse("jump"),
+		"s":       actions.MustParse("random"),
+		"r":       actions.MustParse("repeat"),
+		"f":       actions.MustParse("favourite"),
+		"P":       actions.MustParse("save-preset"),
+		"<Space>": actions.MustParse("toggle"),
+		"n":       actions.MustParse("next"),
+		"b":       actions.MustParse("prev"),
+		"+":       actions.MustParse("volume +5"),
+		"-":       actions.MustParse("volume -5"),
+		"m":       actions.MustParse("mute toggle"),
+		"t":       actions.MustParse("theme next"),
+	}
+	for i := 1; i <= 9; i++ {
+		n := strconv.Itoa(i)
+		keys[n] = actions.MustParse("view " + n)
+		keys[fmt.Sprintf("<F%d>", i)] = actions.MustParse("preset " + n)
+	}
+	return keys
+}
+
+// KeyMap returns the default keys with the configured ones applied
+func (c Config) KeyMap() map[string]actions.Action {
+	keys := DefaultKeys()
+	for key, action := range c.Keys {
+		if action == nil {
+			delete(keys, key)
+			continue
+		}
+		keys[key] = *action
+	}
+	return keys
+}
+
+func chordKey(buttons []int) string {
+	sorted := append([]int(nil), buttons...)
+	sort.Ints(sorted)
+	parts := make([]string, len(sorted))
+	for i, b := range sorted {
+		parts[i] = strconv.Itoa(b)
+	}
+	return strings.Join(parts, "+")
+}
+
+// GestureAction returns the action bound to a gesture
+func (c Config) GestureAction(g multiplexer.Gesture) (actions.Action, bool) {
+	if g.Kind == multiplexer.Chord {
+		key := chordKey(g.Buttons)
+		for _, chord := range c.Chords {
+			if chordKey(chord.Buttons) == key {
+				return chord.Action, true
+			}
+		}
+		return actions.Action{}, false
+	}
+	for _, b := range c.Buttons {
+		if b.Button != g.Index() {
+			continue
+		}
+		var action *actions.Action
+		switch g.Kind {
+		case multiplexer.ShortPress:
+			action = b.Short
+		case multiplexer.LongPress:
+			action = b.Long
+		case multiplexer.RepeatPress:
+			action = b.Repeat
+		case multiplexer.DoublePress:
+			action = b.Double
+		}
+		if action == nil {
+			return actions.Action{}, false
+		}
+		return *action, true
+	}
+	return actions.Action{}, false
+}
+
+// Thresholds returns the per button thresholds for the recognizer
+func (c Config) Thresholds() map[int]multiplexer.Thresholds {
+	thresholds := make(map[int]multiplexer.Thresholds)
+	for _, b := range c.Buttons {
+		t := multiplexer.DefaultThresholds
+		if b.Hold > 0 {
+			t.Long = b.Hold
+		}
+		t.Repeat = b.RepeatEvery
+		t.Double = b.DoubleWithin
+		thresholds[b.Button] = t
+	}
+	return thresholds
+}
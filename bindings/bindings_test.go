@@ -0,0 +1,215 @@
+package bindings
+
+import (
+	"context"
+	"io"
+	"log"
+	"net"
+	"sync"
+	"testing"
+	"time"
+
+	"volumgui/actions"
+	"volumgui/client"
+	"volumgui/multiplexer"
+)
+
+const testConfig = `
+keys:
+  "<Space>": pause
+  m: null
+  x: volume +10
+multiplexer:
+  chips: 1
+  reads_per_sec: 500
+  debounce: 1ms
+buttons:
+  - button: 2
+    hold: 1s
+    short: preset 1
+    long: save-preset 1
+  - button: 3
+    short: next
+chords:
+  - buttons: [4, 3]
+    action: random toggle
+encoders:
+  - a: 0
+    b: 1
+    cw: next
+    ccw: prev
+lirc:
+  buttons:
+    KEY_PLAY: play
+    KEY_VOLUMEUP: volume +2
+`
+
+func Test_Parse(t *testing.T) {
+	config, err := Parse([]byte(testConfig))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	keys := config.KeyMap()
+	if keys["<Space>"].Name != actions.Pause || keys["x"].Number != 10 {
+		t.Fatalf("configured keys not applied: %v %v", keys["<Space>"], keys["x"])
+	}
+	if _, ok := keys["m"]; ok {
+		t.Fatal("null did not unbind m")
+	}
+	if keys["q"].Name != actions.Quit {
+		t.Fatal("default keys missing")
+	}
+
+	gestures := []struct {
+		gesture multiplexer.Gesture
+		want    string
+	}{
+		{multiplexer.Gesture{Kind: multiplexer.ShortPress, Buttons: []int{2}}, "preset 1"},
+		{multiplexer.Gesture{Kind: multiplexer.LongPress, Buttons: []int{2}}, "save-preset 1"},
+		{multiplexer.Gesture{Kind: multiplexer.Chord, Buttons: []int{3, 4}}, "random toggle"},
+		{multiplexer.Gesture{Kind: multiplexer.LongPress, Buttons: []int{3}}, ""},
+	}
+	for _, g := range gestures {
+		action, ok := config.GestureAction(g.gesture)
+		if got := action.String(); ok != (g.want != "") || got != g.want {
+			t.Errorf("%s %v: expected %q, got %q", g.gesture.Kind, g.gesture.Buttons, g.want, got)
+		}
+	}
+	if th := config.Thresholds()[2]; th.Long != time.Second {
+		t.Fatalf("unexpected thresholds %+v", th)
+	}
+}
+
+func Test_ParseErrors(t *testing.T) {
+	for _, config := range []string{
+		"keys:\n  x: dance\n",
+		"buttons:\n  - button: 1\n    short: play\n",
+		"multiplexer:\n  chips: 0\n  reads_per_sec: 100\n",
+		"multiplexer:\n  chips: 1\n  reads_per_sec: 100\nencoders:\n  - a: 0\n    b: 1\n",
+		"unknown: 1\n",
+	} {
+		if _, err := Parse([]byte(config)); err == nil {
+			t.Errorf("expected an error for %q", config)
+		}
+	}
+	if config, err := Parse(nil); err != nil || len(config.KeyMap()) != len(DefaultKeys()) {
+		t.Fatalf("empty config: %v", err)
+	}
+}
+
+// client recording its calls on a channel
+type callClient struct {
+	client.ClientInterface
+	calls chan string
+}
+
+func (c *callClient) Play(ctx context.Context) error { c.calls <- "play"; return nil }
+func (c *callClient) Next(ctx context.Context) error { c.calls <- "next"; return nil }
+func (c *callClient) SetVolume(ctx context.Context, volume int, mute bool) error {
+	c.calls <- "volume"
+	return nil
+}
+
+func newTestInputs(t *testing.T) (*Inputs, *callClient, chan bool) {
+	t.Helper()
+	config, err := Parse([]byte(testConfig))
+	if err != nil {
+		t.Fatal(err)
+	}
+	c := callClient{calls: make(chan string, 16)}
+	logger := log.New(io.Discard, "", 0)
+	runner := actions.NewRunner(&c, func() client.State { return client.State{} }, nil, logger, logger)
+	done := make(chan bool)
+	t.Cleanup(func() { close(done) })
+	return NewInputs(config, runner, done, logger, logger), &c, done
+}
+
+func expectCall(t *testing.T, c *callClient, want string) {
+	t.Helper()
+	select {
+	case got := <-c.calls:
+		if got != want {
+			t.Fatalf("expected %s, got %s", want, got)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatalf("timed out waiting for %s", want)
+	}
+}
+
+func Test_InputsPiso(t *testing.T) {
+	in, c, _ := newTestInputs(t)
+
+	// button 3 pressed and released, then one clockwise detent
+	bus := multiplexer.NewFakeSPI()
+	for _, frame := range []byte{0x03, 0x0b, 0x0b, 0x0b, 0x03, 0x03, 0x03, 0x02, 0x00, 0x01, 0x03} {
+		bus.Push([]byte{frame}, []byte{frame})
+	}
+	piso, err := multiplexer.NewPiso(&multiplexer.FakePin{}, 1, bus, 500, &sync.WaitGroup{}, in.ErrorLog)
+	if err != nil {
+		t.Fatal(err)
+	}
+	in.AttachPiso(piso)
+
+	expectCall(t, c, "next")
+	expectCall(t, c, "next")
+}
+
+func Test_InputsLirc(t *testing.T) {
+	in, c, _ := newTestInputs(t)
+	remote, lircd := net.Pipe()
+	go in.ListenLirc(remote)
+
+	lines := "0000000000f40bf0 00 KEY_PLAY devinput\n" +
+		"0000000000f40bf0 01 KEY_PLAY devinput\n" +
+		"garbage\n" +
+		"0000000000f40bf1 00 KEY_VOLUMEUP devinput\n"
+	if _, err := lircd.Write([]byte(lines)); err != nil {
+		t.Fatal(err)
+	}
+	expectCall(t, c, "play")
+	expectCall(t, c, "volume")
+	lircd.Close()
+}
+
+func Test_InputsBusyPlayer(t *testing.T) {
+	in, c, _ := newTestInputs(t)
+
+	// nobody reads the calls, the player stops answering once they fill up
+	returned := make(chan bool)
+	go func() {
+		for i := 0; i < 2*(cap(c.calls)+actionQueue); i++ {
+			in.run(actions.MustParse("play"))
+		}
+		close(returned)
+	}()
+	select {
+	case <-returned:
+	case <-time.After(2 * time.Second):
+		t.Fatal("inputs blocked on the player")
+	}
+	expectCall(t, c, "play")
+}
+
+func Test_InputsKnobBehind(t *testing.T) {
+	in, _, _ := newTestInputs(t)
+	knob := make(chan multiplexer.EncoderEvent, 1)
+	in.knobs[0] = knob
+
+	// nobody reads the knob, later turns are folded into the queued one
+	returned := make(chan bool)
+	go func() {
+		for i := 0; i < 5; i++ {
+			in.turn(multiplexer.EncoderEvent{Encoder: 0, Detents: 1, Delta: 2})
+		}
+		close(returned)
+	}()
+	select {
+	case <-returned:
+	case <-time.After(2 * time.Second):
+		t.Fatal("inputs blocked on the knob")
+	}
+	if event := <-knob; event.Detents != 5 || event.Delta != 10 {
+		t.Fatalf("expected the turns to be folded, got %+v", event)
+	}
+}
@@ -0,0 +1,216 @@
+package bindings
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"volumgui/actions"
+	"volumgui/multiplexer"
+
+	"github.com/stianeikeland/go-rpio/v4"
+)
+
+const (
+	actionTimeout = 5 * time.Second
+	actionQueue   = 16 // actions waiting to run, inputs beyond are dropped
+)
+
+// Inputs runs the actions bound to the multiplexer and lirc inputs
+type Inputs struct {
+	Config   Config
+	Runner   *actions.Runner
+	DoneChan <-chan bool
+	InfoLog  *log.Logger
+	ErrorLog *log.Logger
+	knobs    map[int]chan multiplexer.EncoderEvent // volume knobs by encoder
+	queue    chan actions.Action                   // actions of the inputs, run in order by work
+}
+
+// NewInputs starts the worker running the actions of the inputs until
+// done_chan is closed
+func NewInputs(config Config, runner *actions.Runner, done_chan <-chan bool, info_log *log.Logger, error_log *log.Logger) *Inputs {
+	in := &Inputs{
+		Config:   config,
+		Runner:   runner,
+		DoneChan: done_chan,
+		InfoLog:  info_log,
+		ErrorLog: error_log,
+		knobs:    make(map[int]chan multiplexer.EncoderEvent),
+		queue:    make(chan actions.Action, actionQueue),
+	}
+	go in.work()
+	return in
+}
+
+// Start opens the configured hardware and handles its inputs until
+// DoneChan is closed
+func (in *Inputs) Start() error {
+	if m := in.Config.Multiplexer; m != nil {
+		bus, err := multiplexer.OpenRpioSPI(rpio.SpiDev(m.Spi), multiplexer.ChipSelect(m.ChipSelect))
+		if err != nil {
+			return err
+		}
+		// the piso has its own wait group, it stops on DoneChan
+		piso, err := multiplexer.NewPiso(multiplexer.NewRpioPin(m.LoadPin), m.Chips, bus, m.ReadsPerSec, &sync.WaitGroup{}, in.ErrorLog)
+		if err != nil {
+			bus.Close()
+			return err
+		}
+		in.AttachPiso(piso)
+		in.InfoLog.Printf("reading %d shift registers at %d/s", m.Chips, m.ReadsPerSec)
+	}
+	if l := in.Config.Lirc; l != nil {
+		socket := l.Socket
+		if socket == "" {
+			socket = DefaultLircSocket
+		}
+		conn, err := net.Dial("unix", socket)
+		if err != nil {
+			return fmt.Errorf("bindings: lirc: %w", err)
+		}
+		go in.ListenLirc(conn)
+		in.InfoLog.Printf("listening to lircd at %s", socket)
+	}
+	return nil
+}
+
+// AttachPiso configures the piso for the bound buttons and encoders, then
+// runs it and the actions of its gestures and turns
+func (in *Inputs) AttachPiso(piso *multiplexer.PISO) {
+	if m := in.Config.Multiplexer; m != nil {
+		if m.Debounce > 0 {
+			piso.Decoder.Debounce = m.Debounce
+		}
+		piso.Decoder.ActiveLow = m.ActiveLow
+	}
+	for index, t := range in.Config.Thresholds() {
+		piso.Recognizer.Buttons[index] = t
+	}
+	for i, e := range in.Config.Encoders {
+		encoder := multiplexer.NewEncoder(e.A, e.B)
+		if e.StepsPerDetent > 0 {
+			encoder.StepsPerDetent = e.StepsPerDetent
+		}
+		piso.Encoders = append(piso.Encoders, encoder)
+
+		if e.VolumeStep != 0 {
+			knob := multiplexer.NewVolumeKnob(in.Runner.Client, in.Runner.State, in.ErrorLog)
+			knob.Step = e.VolumeStep
+			knob.ErrChan = in.Runner.ErrChan
+			events := make(chan multiplexer.EncoderEvent, 16)
+			in.knobs[i] = events
+			go knob.Run(events, in.DoneChan)
+		}
+	}
+
+	piso.Start()
+	go in.route(piso)
+}
+
+func (in *Inputs) route(piso *multiplexer.PISO) {
+	for {
+		select {
+		case gesture := <-piso.GestureChan:
+			if action, ok := in.Config.GestureAction(gesture); ok {
+				in.run(action)
+			}
+		case event := <-piso.EncoderChan:
+			in.turn(event)
+		case <-in.DoneChan:
+			close(piso.DoneChan)
+			return
+		}
+	}
+}
+
+// volume knobs get the turn, other encoders run their action per detent
+func (in *Inputs) turn(event multiplexer.EncoderEvent) {
+	if knob, ok := in.knobs[event.Encoder]; ok {
+		for {
+			select {
+			case knob <- event:
+				return
+			default:
+			}
+			// the knob is behind, fold the turn into one it has not read
+			select {
+			case queued := <-knob:
+				event.Delta += queued.Delta
+				event.Detents += queued.Detents
+			default:
+			}
+		}
+	}
+	e := in.Config.Encoders[event.Encoder]
+	action, count := e.Clockwise, event.Delta
+	if count < 0 {
+		action, count = e.CounterClock, -count
+	}
+	for i := 0; i < count; i++ {
+		in.run(*action)
+	}
+}
+
+// run queues the action, the inputs keep being read while the player
+// is slow to answer
+func (in *Inputs) run(action actions.Action) {
+	select {
+	case in.queue <- action:
+	default:
+		in.ErrorLog.Printf("bindings: dropped %s, %d actions are waiting", action, actionQueue)
+	}
+}
+
+func (in *Inputs) work() {
+	for {
+		select {
+		case action := <-in.queue:
+			ctx, cancel := context.WithTimeout(context.Background(), actionTimeout)
+			if err := in.Runner.Run(ctx, action); err != nil {
+				in.ErrorLog.Println(err)
+			}
+			cancel()
+		case <-in.DoneChan:
+			return
+		}
+	}
+}
+
+// ListenLirc runs the actions of the remote buttons lircd reports on r,
+// lines look like "0000000000f40bf0 00 KEY_PLAY remote"
+func (in *Inputs) ListenLirc(r io.ReadCloser) {
+	go func() {
+		<-in.DoneChan
+		r.Close()
+	}()
+
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) != 4 {
+			continue
+		}
+		repeat, err := strconv.ParseUint(fields[1], 16, 32)
+		if err != nil {
+			continue
+		}
+		action, ok := in.Config.Lirc.Buttons[fields[2]]
+		if !ok || (repeat > 0 && !in.Config.Lirc.Repeat) {
+			continue
+		}
+		in.run(action)
+	}
+	select {
+	case <-in.DoneChan:
+	default:
+		in.ErrorLog.Printf("lirc: connection closed: %v", scanner.Err())
+	}
+}
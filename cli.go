@@ -0,0 +1,190 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"text/template"
+	"time"
+
+	"volumgui/actions"
+	"volumgui/client"
+	"volumgui/config"
+	"volumgui/ui"
+)
+
+const (
+	stateTimeout  = 10 * time.Second
+	commandUsage  = "usage: volumgui [flags] [status [--json|--format template] | watch [--format template] | config print | action...]"
+	defaultFormat = `{{.Status}}: {{.Artist}} - {{.Title}}
+album:  {{.Album}}
+time:   {{clock .Elapsed}}{{if .Duration}} / {{clock .Length}}{{end}}
+volume: {{.Volume}}{{if .Mute}} (muted){{end}}
+`
+)
+
+// status printed by the status and watch commands
+type cliStatus struct {
+	client.State
+	Conn string `json:"conn"`
+}
+
+var templateFuncs = template.FuncMap{
+	"clock": func(d time.Duration) string { return ui.PlayDuration{Duration: d}.String() },
+}
+
+// runCommand runs a command line like "status --json" or "volume +5"
+// without the display and returns the exit code
+func (app *app) runCommand(args []string, stdout io.Writer, stderr io.Writer) int {
+	var err error
+	switch args[0] {
+	case "status":
+		err = app.status(args[1:], stdout)
+	case "watch":
+		err = app.watch(args[1:], stdout)
+	case "help":
+		fmt.Fprintln(stdout, commandUsage)
+		return 0
+	default:
+		err = app.action(strings.Join(args, " "))
+	}
+
+	var usage usageError
+	switch {
+	case errors.As(err, &usage):
+		fmt.Fprintln(stderr, err)
+		fmt.Fprintln(stderr, commandUsage)
+		return 2
+	case err != nil:
+		fmt.Fprintln(stderr, err)
+		return 1
+	}
+	return 0
+}
+
+type usageError struct{ error }
+
+// configCommand runs "config print", it needs neither the client nor
+// the logs
+func configCommand(cfg *config.Config, args []string, stdout io.Writer, stderr io.Writer) int {
+	if len(args) != 1 || args[0] != "print" {
+		fmt.Fprintln(stderr, "usage: volumgui [flags] config print")
+		return 2
+	}
+	if err := cfg.Print(stdout); err != nil {
+		fmt.Fprintln(stderr, err)
+		return 1
+	}
+	return 0
+}
+
+// parseFormat parses the flags of the status and watch commands, a nil
+// template means json output
+func parseFormat(name string, args []string, format string) (*template.Template, error) {
+	flags := flag.NewFlagSet(name, flag.ContinueOnError)
+	flags.SetOutput(io.Discard)
+	as_json := flags.Bool("json", false, "print the state as json")
+	flags.StringVar(&format, "format", format, "go template of the output")
+	if err := flags.Parse(args); err != nil {
+		return nil, usageError{err}
+	}
+	if flags.NArg() > 0 {
+		return nil, usageError{fmt.Errorf("%s: unexpected arguments %v", name, flags.Args())}
+	}
+	if *as_json || format == "" {
+		return nil, nil
+	}
+	// formats from the command line end up on their own line
+	if !strings.HasSuffix(format, "\n") {
+		format += "\n"
+	}
+	t, err := template.New(name).Funcs(templateFuncs).Parse(format)
+	if err != nil {
+		return nil, usageError{err}
+	}
+	return t, nil
+}
+
+// printStatus prints status with t, or as a json line without one
+func printStatus(w io.Writer, t *template.Template, status cliStatus) error {
+	if t == nil {
+		return json.NewEncoder(w).Encode(status)
+	}
+	return t.Execute(w, status)
+}
+
+// waitState waits for the first state of the client
+func (app *app) waitState() error {
+	states := app.Hub.Subscribe()
+	defer app.Hub.Unsubscribe(states)
+	select {
+	case <-states:
+		return nil
+	case <-time.After(stateTimeout):
+		return errors.New("timed out waiting for the volumio state")
+	}
+}
+
+func (app *app) status(args []string, stdout io.Writer) error {
+	t, err := parseFormat("status", args, defaultFormat)
+	if err != nil {
+		return err
+	}
+	if err := app.waitState(); err != nil {
+		return err
+	}
+	return printStatus(stdout, t, cliStatus{State: app.Hub.Current(), Conn: app.Hub.Conn().String()})
+}
+
+// watch prints a json line, or the format, for every change until
+// interrupted
+func (app *app) watch(args []string, stdout io.Writer) error {
+	t, err := parseFormat("watch", args, "")
+	if err != nil {
+		return err
+	}
+	quit := make(chan os.Signal, 1)
+	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
+	states := app.Hub.Subscribe()
+	defer app.Hub.Unsubscribe(states)
+	conns := app.Hub.SubscribeConn()
+	defer app.Hub.UnsubscribeConn(conns)
+
+	for {
+		select {
+		case <-states:
+		case <-conns:
+		case <-quit:
+			return nil
+		}
+		// a closed pipe ends the watch
+		if err := printStatus(stdout, t, cliStatus{State: app.Hub.State(), Conn: app.Hub.Conn().String()}); err != nil {
+			return err
+		}
+	}
+}
+
+// action runs an action like "volume +5" once the state is known, relative
+// actions depend on it
+func (app *app) action(line string) error {
+	a, err := actions.Parse(line)
+	if err != nil {
+		return usageError{err}
+	}
+	if a.UI() {
+		return usageError{fmt.Errorf("%s needs the display", a)}
+	}
+	if err := app.waitState(); err != nil {
+		return err
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), stateTimeout)
+	defer cancel()
+	return app.Runner.Run(ctx, a)
+}
@@ -0,0 +1,57 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"volumgui/client"
+)
+
+func Test_StatusFormat(t *testing.T) {
+	status := cliStatus{
+		State: client.State{Status: "pause", Artist: "Cream", Title: "Crossroads", Album: "Wheels of Fire", Seek: 75000, Duration: 257, Volume: 30, Mute: true},
+		Conn:  "connected",
+	}
+	tests := []struct {
+		args []string
+		want string
+	}{
+		{nil, "pause: Cream - Crossroads\nalbum:  Wheels of Fire\ntime:   01:15 / 04:17\nvolume: 30 (muted)\n"},
+		{[]string{"--format", "{{.Artist}} - {{.Title}}"}, "Cream - Crossroads\n"},
+		{[]string{"--format", "{{clock .Elapsed}}\n"}, "01:15\n"},
+	}
+	for _, test := range tests {
+		format, err := parseFormat("status", test.args, defaultFormat)
+		if err != nil {
+			t.Fatalf("%v: %v", test.args, err)
+		}
+		var out bytes.Buffer
+		if err := printStatus(&out, format, status); err != nil {
+			t.Fatal(err)
+		}
+		if out.String() != test.want {
+			t.Errorf("%v: expected %q, got %q", test.args, test.want, out.String())
+		}
+	}
+
+	// json lines for scripts
+	format, err := parseFormat("status", []string{"--json"}, defaultFormat)
+	if err != nil || format != nil {
+		t.Fatalf("unexpected format %v %v", format, err)
+	}
+	var out bytes.Buffer
+	printStatus(&out, nil, status)
+	var decoded cliStatus
+	if err := json.Unmarshal(out.Bytes(), &decoded); err != nil || decoded != status {
+		t.Fatalf("unexpected json %s: %v", out.String(), err)
+	}
+
+	for _, args := range [][]string{{"--bogus"}, {"extra"}, {"--format", "{{.Title"}} {
+		var usage usageError
+		if _, err := parseFormat("status", args, defaultFormat); !errors.As(err, &usage) {
+			t.Errorf("%v: expected a usage error, got %v", args, err)
+		}
+	}
+}
@@ -1,15 +1,73 @@
 package client
 
+import (
+	"context"
+	"time"
+)
+
+// ClientInterface is implemented by all volumio backends, commands
+// return ErrUnavailable, ErrBadResponse or ErrInvalidArgument wrapped
+// with details of what failed
 type ClientInterface interface {
 	Connect()
+	// Close stops the client and may be called more than once. Commands
+	// issued after it fail with ErrUnavailable and the client stops
+	// sending states, StateChan and ConnChan stay open for readers that
+	// stop on their own done channel
 	Close()
-	Play()
-	Stop()
-	Pause()
-	Next()
-	Prev()
-	Mute()
-	UnMute()
-	SetVolume(int, bool)
-	GetState()
+	Play(context.Context) error
+	Stop(context.Context) error
+	Pause(context.Context) error
+	Next(context.Context) error
+	Prev(context.Context) error
+	Mute(context.Context) error
+	UnMute(context.Context) error
+	SetVolume(context.Context, int, bool) error
+	Seek(context.Context, time.Duration) error
+	SeekBy(context.Context, time.Duration) error
+	SetRandom(context.Context, bool) error
+	SetRepeat(context.Context, bool) error
+	SetRepeatSingle(context.Context, bool) error
+	GetState(context.Context) error
+}
+
+// PushInterface is implemented by clients that send state changes on
+// their own, these don't need to be polled with GetState
+type PushInterface interface {
+	PushesState() bool
+}
+
+// QueueInterface is implemented by clients that can manage the play
+// queue, indices start at zero
+type QueueInterface interface {
+	GetQueue(context.Context) (Queue, error)
+	AddToQueue(context.Context, ...QueueItem) error
+	RemoveFromQueue(context.Context, int) error
+	MoveQueue(ctx context.Context, from int, to int) error
+	ClearQueue(context.Context) error
+	PlayIndex(context.Context, int) error
+	AddPlay(context.Context, QueueItem) error        // add to the queue and play it
+	ReplaceAndPlay(context.Context, QueueItem) error // replace the queue and play it
+}
+
+// LibraryInterface is implemented by clients that can browse the
+// volumio library and music sources
+type LibraryInterface interface {
+	GetBrowseSources(context.Context) ([]BrowseSource, error)
+	BrowseLibrary(ctx context.Context, uri string) (BrowseResult, error)
+	Search(ctx context.Context, query string) (SearchResult, error)
+}
+
+// PlaylistInterface is implemented by clients that manage playlists and
+// favourites, playlists are identified by name
+type PlaylistInterface interface {
+	ListPlaylists(context.Context) ([]Playlist, error)
+	CreatePlaylist(ctx context.Context, name string) error
+	DeletePlaylist(ctx context.Context, name string) error
+	AddToPlaylist(ctx context.Context, name string, item QueueItem) error
+	RemoveFromPlaylist(ctx context.Context, name string, item QueueItem) error
+	PlayPlaylist(ctx context.Context, name string) error
+	AddToFavourites(context.Context, QueueItem) error
+	RemoveFromFavourites(context.Context, QueueItem) error
+	PlayFavourites(context.Context) error
 }
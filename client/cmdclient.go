@@ -1,14 +1,20 @@
 package client
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"log"
 	"os/exec"
 	"strconv"
 	"strings"
 	"sync"
+	"time"
 )
 
+// time after which a hanging volumio cli is killed
+const defaultCmdTimeout = 5 * time.Second
+
 // generic respose object
 type CmdResponse struct {
 	Time     int    `json:"time"`     // time of the response
@@ -18,103 +24,190 @@ type CmdResponse struct {
 type CmdClient struct {
 	ClientInterface
 	State     State
+	mu        sync.Mutex    // guards State, read by commands from other goroutines
+	Current   func() State  // position SeekBy starts from, the last polled state unless set
+	Timeout   time.Duration // upper bound for a single cli call
 	Wait      *sync.WaitGroup
 	InfoLog   *log.Logger
 	ErrorLog  *log.Logger
 	StateChan chan State
 	DoneChan  chan bool
+	closed    chan bool // closed by Close
+	closeOnce sync.Once
 }
 
-func NewCmdClient(wg *sync.WaitGroup, done_chan chan bool, info_log *log.Logger, error_log *log.Logger) CmdClient {
+func NewCmdClient(wg *sync.WaitGroup, done_chan chan bool, info_log *log.Logger, error_log *log.Logger) *CmdClient {
 	state_chan := make(chan State)
-	cmd_client := CmdClient{
+	cmd_client := &CmdClient{
+		Timeout:   defaultCmdTimeout,
 		Wait:      wg,
 		StateChan: state_chan,
 		DoneChan:  done_chan,
+		closed:    make(chan bool),
 		InfoLog:   info_log,
 		ErrorLog:  error_log,
 	}
+	cmd_client.Current = cmd_client.currentState
 	return cmd_client
 }
 
-func (c *CmdClient) issueCmd(cmd string) []byte {
-	out, err := exec.Command("bash", "-c", cmd).Output()
+func (c *CmdClient) issueCmd(ctx context.Context, action cmd_line, cmd string) ([]byte, error) {
+	timeout := c.Timeout
+	if timeout == 0 {
+		timeout = defaultCmdTimeout
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	command := exec.CommandContext(ctx, "bash", "-c", cmd)
+	// children of bash keep the output open after bash is killed
+	command.WaitDelay = 500 * time.Millisecond
+	out, err := command.Output()
+	if ctx.Err() != nil {
+		return nil, cmdError(action, ErrUnavailable, ctx.Err())
+	}
 	if err != nil {
-		c.ErrorLog.Println(err)
+		var exit_err *exec.ExitError
+		if errors.As(err, &exit_err) && len(exit_err.Stderr) > 0 {
+			err = errors.New(strings.TrimSpace(string(exit_err.Stderr)))
+		}
+		return nil, cmdError(action, ErrUnavailable, err)
 	}
 
-	return out
+	return out, nil
 }
 
-func (c *CmdClient) execute(action cmd_line, args ...string) []byte {
+func (c *CmdClient) execute(ctx context.Context, action cmd_line, args ...string) ([]byte, error) {
+	select {
+	case <-c.closed:
+		return nil, cmdError(action, ErrUnavailable, errClosed)
+	default:
+	}
 	cmd_string := "volumio " + action.String()
 	if len(args) > 0 {
 		cmd_string += " " + strings.Join(args, " ")
 	}
-	resp := c.issueCmd(cmd_string)
 
-	return resp
+	return c.issueCmd(ctx, action, cmd_string)
 }
 
-func (c *CmdClient) Connect() {
-	panic("Method not implemented")
+// run a command whose output is not needed
+func (c *CmdClient) run(ctx context.Context, action cmd_line, args ...string) error {
+	_, err := c.execute(ctx, action, args...)
+	return err
 }
 
+// the cli runs on the player, there is nothing to connect
+func (c *CmdClient) Connect() {}
+
+// DoneChan belongs to the caller and is left open
 func (c *CmdClient) Close() {
-	close(c.DoneChan)
-	close(c.StateChan)
+	c.closeOnce.Do(func() {
+		close(c.closed)
+	})
 }
 
-func (c *CmdClient) Play() {
-	c.execute(PLAY_L)
+func (c *CmdClient) Play(ctx context.Context) error {
+	return c.run(ctx, PLAY_L)
 }
 
-func (c *CmdClient) Stop() {
-	c.execute(STOP_L)
+func (c *CmdClient) Stop(ctx context.Context) error {
+	return c.run(ctx, STOP_L)
 }
 
-func (c *CmdClient) Pause() {
-	c.execute(PAUSE_L)
+func (c *CmdClient) Pause(ctx context.Context) error {
+	return c.run(ctx, PAUSE_L)
 }
 
-func (c *CmdClient) Next() {
-	c.execute(NEXT_L)
+func (c *CmdClient) Next(ctx context.Context) error {
+	return c.run(ctx, NEXT_L)
 }
 
-func (c *CmdClient) Prev() {
-	c.execute(PREV_L)
+func (c *CmdClient) Prev(ctx context.Context) error {
+	return c.run(ctx, PREV_L)
 }
 
-func (c *CmdClient) Mute() {
-	c.execute(MUTE_L)
+func (c *CmdClient) Mute(ctx context.Context) error {
+	return c.run(ctx, MUTE_L)
 }
 
-func (c *CmdClient) UnMute() {
-	c.execute(UNMUTE_L)
+func (c *CmdClient) UnMute(ctx context.Context) error {
+	return c.run(ctx, UNMUTE_L)
 }
 
-func (c *CmdClient) SetVolume(volume int, mute bool) {
-	if volume > 100 || volume < 0 {
-		c.ErrorLog.Printf("illegal volume value: %d", volume)
+func (c *CmdClient) SetVolume(ctx context.Context, volume int, mute bool) error {
+	if err := validateVolume(VOLUME_L, volume); err != nil {
+		return err
 	}
 	if mute {
-		c.Mute()
+		if err := c.Mute(ctx); err != nil {
+			return err
+		}
 	} else {
-		c.UnMute()
+		if err := c.UnMute(ctx); err != nil {
+			return err
+		}
 	}
-	c.execute(VOLUME_L, strconv.Itoa(volume))
+	return c.run(ctx, VOLUME_L, strconv.Itoa(volume))
 }
 
-func (c *CmdClient) GetState() {
-	state := c.execute(GETSTATE_L)
+// seek to an absolute position, the cli expects whole seconds
+func (c *CmdClient) Seek(ctx context.Context, position time.Duration) error {
+	if err := validatePosition(SEEK_L, position); err != nil {
+		return err
+	}
+	return c.run(ctx, SEEK_L, strconv.Itoa(int(position.Seconds())))
+}
 
-	var current_state State
+// seek relative to the current position
+func (c *CmdClient) SeekBy(ctx context.Context, delta time.Duration) error {
+	return c.Seek(ctx, c.Current().SeekTarget(delta))
+}
+
+func (c *CmdClient) SetRandom(ctx context.Context, enabled bool) error {
+	return c.run(ctx, SETRANDOM_L, strconv.FormatBool(enabled))
+}
 
+func (c *CmdClient) SetRepeat(ctx context.Context, enabled bool) error {
+	return c.run(ctx, SETREPEAT_L, strconv.FormatBool(enabled))
+}
+
+// repeating a single item implies repeat, turning it off keeps repeat as is
+func (c *CmdClient) SetRepeatSingle(ctx context.Context, enabled bool) error {
+	repeat := c.currentState().Repeat || enabled
+	return c.run(ctx, SETREPEAT_L, strconv.FormatBool(repeat), strconv.FormatBool(enabled))
+}
+
+// get the state and send it on StateChan if it changed, a state that
+// can't be decoded is reported and not sent
+func (c *CmdClient) GetState(ctx context.Context) error {
+	state, err := c.execute(ctx, GETSTATE_L)
+	if err != nil {
+		return err
+	}
+
+	var current_state State
 	if err := json.Unmarshal(state, &current_state); err != nil {
-		c.ErrorLog.Printf("error processing state: %s", err)
+		return cmdError(GETSTATE_L, ErrBadResponse, err)
 	}
-	if current_state != c.State {
-		c.State = current_state
-		c.StateChan <- c.State
+	if current_state != c.currentState() {
+		select {
+		case c.StateChan <- current_state:
+			c.mu.Lock()
+			c.State = current_state
+			c.mu.Unlock()
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-c.closed:
+			return cmdError(GETSTATE_L, ErrUnavailable, errClosed)
+		}
 	}
+	return nil
+}
+
+// copy of the last polled state
+func (c *CmdClient) currentState() State {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.State
 }
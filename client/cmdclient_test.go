@@ -0,0 +1,92 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+)
+
+// put a fake volumio cli running script in front of PATH
+func fakeVolumioCli(t *testing.T, script string) {
+	t.Helper()
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "volumio"), []byte("#!/bin/sh\n"+script+"\n"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("PATH", dir+string(os.PathListSeparator)+os.Getenv("PATH"))
+}
+
+func newTestCmdClient() *CmdClient {
+	logger := log.New(io.Discard, "", 0)
+	return NewCmdClient(&sync.WaitGroup{}, make(chan bool), logger, logger)
+}
+
+func Test_CmdClientTimeout(t *testing.T) {
+	fakeVolumioCli(t, "sleep 10")
+	c := newTestCmdClient()
+	c.Timeout = 100 * time.Millisecond
+
+	start := time.Now()
+	err := c.Play(context.Background())
+	if !errors.Is(err, ErrUnavailable) {
+		t.Fatalf("expected ErrUnavailable, got %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Fatalf("hanging cli was not killed, took %s", elapsed)
+	}
+}
+
+func Test_CmdClientBadState(t *testing.T) {
+	fakeVolumioCli(t, "echo 'volumio is starting'")
+	c := newTestCmdClient()
+
+	done := make(chan error)
+	go func() {
+		done <- c.GetState(context.Background())
+	}()
+	select {
+	case state := <-c.StateChan:
+		t.Fatalf("undecodable state was sent: %+v", state)
+	case err := <-done:
+		if !errors.Is(err, ErrBadResponse) {
+			t.Fatalf("expected ErrBadResponse, got %v", err)
+		}
+	}
+}
+
+func Test_CmdClientInvalidVolume(t *testing.T) {
+	fakeVolumioCli(t, "exit 1")
+	c := newTestCmdClient()
+
+	if err := c.SetVolume(context.Background(), -1, false); !errors.Is(err, ErrInvalidArgument) {
+		t.Fatalf("expected ErrInvalidArgument, got %v", err)
+	}
+	if err := c.Next(context.Background()); !errors.Is(err, ErrUnavailable) {
+		t.Fatalf("expected ErrUnavailable, got %v", err)
+	}
+}
+
+func Test_CmdClientClose(t *testing.T) {
+	fakeVolumioCli(t, `echo '{"status":"play"}'`)
+	c := newTestCmdClient()
+
+	c.Close()
+	c.Close()
+	if err := c.Play(context.Background()); !errors.Is(err, ErrUnavailable) {
+		t.Fatalf("expected ErrUnavailable, got %v", err)
+	}
+	if err := c.GetState(context.Background()); !errors.Is(err, ErrUnavailable) {
+		t.Fatalf("expected ErrUnavailable, got %v", err)
+	}
+	select {
+	case <-c.DoneChan:
+		t.Fatal("DoneChan of the caller was closed")
+	default:
+	}
+}
@@ -0,0 +1,57 @@
+package client
+
+import (
+	"math/rand"
+	"time"
+)
+
+// state of a connection to volumio
+type ConnState int
+
+const (
+	Disconnected ConnState = iota
+	Connecting
+	Connected
+)
+
+func (s ConnState) String() string {
+	switch s {
+	case Connecting:
+		return "connecting"
+	case Connected:
+		return "connected"
+	default:
+		return "disconnected"
+	}
+}
+
+// exponential backoff with jitter between reconnection attempts
+type backoff struct {
+	min     time.Duration // delay of the first attempt
+	max     time.Duration // upper bound of the delay
+	current time.Duration // delay without jitter
+}
+
+func newBackoff(min time.Duration, max time.Duration) *backoff {
+	return &backoff{min: min, max: max}
+}
+
+// next returns the delay before the next attempt, the second half of
+// the delay is randomized so clients don't reconnect in lockstep
+func (b *backoff) next() time.Duration {
+	switch {
+	case b.current == 0:
+		b.current = b.min
+	case b.current < b.max:
+		b.current *= 2
+	}
+	if b.current > b.max {
+		b.current = b.max
+	}
+	half := b.current / 2
+	return half + time.Duration(rand.Int63n(int64(half)+1))
+}
+
+func (b *backoff) reset() {
+	b.current = 0
+}
@@ -0,0 +1,70 @@
+package client
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+var (
+	ErrUnavailable     = errors.New("volumio unavailable") // volumio could not be reached or timed out
+	ErrBadResponse     = errors.New("bad response")        // volumio answered with something unexpected
+	ErrInvalidArgument = errors.New("invalid argument")    // the command was rejected before sending it
+	ErrUnsupported     = errors.New("not supported")       // the backend has no equivalent of the command
+)
+
+// cause of commands issued after Close
+var errClosed = errors.New("client closed")
+
+// wrap a sentinel error with the failed command and the cause
+func cmdError(cmd fmt.Stringer, kind error, cause error) error {
+	if cause == nil {
+		return fmt.Errorf("%s: %w", cmd, kind)
+	}
+	return fmt.Errorf("%s: %w: %s", cmd, kind, cause)
+}
+
+func validateVolume(cmd fmt.Stringer, volume int) error {
+	if volume > 100 || volume < 0 {
+		return cmdError(cmd, ErrInvalidArgument, fmt.Errorf("volume %d out of range 0-100", volume))
+	}
+	return nil
+}
+
+func validatePosition(cmd fmt.Stringer, position time.Duration) error {
+	if position < 0 {
+		return cmdError(cmd, ErrInvalidArgument, fmt.Errorf("negative position %s", position))
+	}
+	return nil
+}
+
+// decode a reply, data that doesn't match v is a bad response
+func decodeReply(cmd fmt.Stringer, data []byte, v interface{}) error {
+	if err := json.Unmarshal(data, v); err != nil {
+		return cmdError(cmd, ErrBadResponse, err)
+	}
+	return nil
+}
+
+func validateIndex(cmd fmt.Stringer, index int) error {
+	if index < 0 {
+		return cmdError(cmd, ErrInvalidArgument, fmt.Errorf("negative queue index %d", index))
+	}
+	return nil
+}
+
+func validateName(cmd fmt.Stringer, name string) error {
+	if strings.TrimSpace(name) == "" {
+		return cmdError(cmd, ErrInvalidArgument, errors.New("empty playlist name"))
+	}
+	return nil
+}
+
+func validateItem(cmd fmt.Stringer, item QueueItem) error {
+	if item.Uri == "" {
+		return cmdError(cmd, ErrInvalidArgument, errors.New("item without uri"))
+	}
+	return nil
+}
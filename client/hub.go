@@ -0,0 +1,170 @@
+package client
+
+import (
+	"sync"
+	"time"
+)
+
+// StateHub fans the states and connection states of a client out to
+// several consumers and keeps the latest ones, slow consumers only ever
+// see the most recent state
+type StateHub struct {
+	mu              sync.Mutex
+	state           State
+	received        time.Time // time the latest state arrived
+	conn            ConnState
+	connReceived    bool
+	subscribers     []chan State
+	connSubscribers []chan ConnState
+	StateChan       <-chan State
+	ConnChan        <-chan ConnState // nil for clients without a connection
+	DoneChan        <-chan bool
+}
+
+func NewStateHub(state_chan <-chan State, conn_chan <-chan ConnState, done_chan <-chan bool) *StateHub {
+	return &StateHub{
+		StateChan: state_chan,
+		ConnChan:  conn_chan,
+		DoneChan:  done_chan,
+	}
+}
+
+// Subscribe returns a channel receiving every new state, the latest state
+// is sent right away if there is one
+func (h *StateHub) Subscribe() <-chan State {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	sub := make(chan State, 1)
+	if !h.received.IsZero() {
+		sub <- h.state
+	}
+	h.subscribers = append(h.subscribers, sub)
+	return sub
+}
+
+// SubscribeConn returns a channel receiving the connection state changes,
+// starting with the latest one, nil for clients without a connection
+func (h *StateHub) SubscribeConn() <-chan ConnState {
+	if h.ConnChan == nil {
+		return nil
+	}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	sub := make(chan ConnState, 1)
+	if h.connReceived {
+		sub <- h.conn
+	}
+	h.connSubscribers = append(h.connSubscribers, sub)
+	return sub
+}
+
+// Unsubscribe stops sending states to sub
+func (h *StateHub) Unsubscribe(sub <-chan State) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	for i, s := range h.subscribers {
+		if s == sub {
+			h.subscribers = append(h.subscribers[:i], h.subscribers[i+1:]...)
+			return
+		}
+	}
+}
+
+// UnsubscribeConn stops sending connection states to sub
+func (h *StateHub) UnsubscribeConn(sub <-chan ConnState) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	for i, s := range h.connSubscribers {
+		if s == sub {
+			h.connSubscribers = append(h.connSubscribers[:i], h.connSubscribers[i+1:]...)
+			return
+		}
+	}
+}
+
+// Conn returns the latest connection state, clients without a
+// connection count as connected
+func (h *StateHub) Conn() ConnState {
+	if h.ConnChan == nil {
+		return Connected
+	}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return h.conn
+}
+
+// State returns the latest state as it was received
+func (h *StateHub) State() State {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return h.state
+}
+
+// Current returns the latest state with the seek position advanced by
+// the time passed since it was received while playing
+func (h *StateHub) Current() State {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if h.received.IsZero() {
+		return h.state
+	}
+	return h.state.Advanced(time.Since(h.received))
+}
+
+// Run forwards states until DoneChan is closed
+func (h *StateHub) Run() {
+	for {
+		select {
+		case state := <-h.StateChan:
+			h.publish(state)
+		case conn := <-h.ConnChan:
+			h.publishConn(conn)
+		case <-h.DoneChan:
+			return
+		}
+	}
+}
+
+func (h *StateHub) publish(state State) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.state = state
+	h.received = time.Now()
+	for _, sub := range h.subscribers {
+		// replace a state the subscriber hasn't picked up yet
+		select {
+		case sub <- state:
+		default:
+			select {
+			case <-sub:
+			default:
+			}
+			sub <- state
+		}
+	}
+}
+
+func (h *StateHub) publishConn(conn ConnState) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.conn = conn
+	h.connReceived = true
+	for _, sub := range h.connSubscribers {
+		select {
+		case sub <- conn:
+		default:
+			select {
+			case <-sub:
+			default:
+			}
+			sub <- conn
+		}
+	}
+}
@@ -0,0 +1,94 @@
+package client
+
+import (
+	"testing"
+	"time"
+)
+
+func Test_StateHub(t *testing.T) {
+	state_chan := make(chan State)
+	done_chan := make(chan bool)
+	defer close(done_chan)
+
+	hub := NewStateHub(state_chan, nil, done_chan)
+	go hub.Run()
+	sub := hub.Subscribe()
+
+	// a slow subscriber only gets the latest state
+	state_chan <- State{Title: "Sleepy Time Time"}
+	state_chan <- State{Title: "Sweet Wine"}
+	state_chan <- State{Title: "Spoonful", Status: "play", Seek: 1000, Duration: 60}
+	for deadline := time.Now().Add(5 * time.Second); hub.State().Title != "Spoonful"; {
+		if time.Now().After(deadline) {
+			t.Fatal("timed out waiting for publish")
+		}
+		time.Sleep(time.Millisecond)
+	}
+	select {
+	case state := <-sub:
+		if state.Title != "Spoonful" {
+			t.Fatalf("expected latest state, got %+v", state)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for state")
+	}
+
+	// late subscribers start with the latest state
+	select {
+	case state := <-hub.Subscribe():
+		if state.Title != "Spoonful" {
+			t.Fatalf("unexpected state %+v", state)
+		}
+	default:
+		t.Fatal("no state for late subscriber")
+	}
+
+	hub.Unsubscribe(sub)
+	state_chan <- State{Title: "Badge"}
+	for deadline := time.Now().Add(5 * time.Second); hub.State().Title != "Badge"; {
+		if time.Now().After(deadline) {
+			t.Fatal("timed out waiting for publish")
+		}
+		time.Sleep(time.Millisecond)
+	}
+	select {
+	case state := <-sub:
+		t.Fatalf("unsubscribed channel received %+v", state)
+	default:
+	}
+
+	state_chan <- State{Title: "Spoonful", Status: "play", Seek: 1000, Duration: 60}
+	time.Sleep(20 * time.Millisecond)
+	if seek := hub.Current().Seek; seek < 1020 {
+		t.Fatalf("seek %d not advanced while playing", seek)
+	}
+	if seek := hub.State().Seek; seek != 1000 {
+		t.Fatalf("received state changed: %d", seek)
+	}
+}
+
+func Test_StateHubConn(t *testing.T) {
+	conn_chan := make(chan ConnState)
+	done_chan := make(chan bool)
+	defer close(done_chan)
+
+	hub := NewStateHub(make(chan State), conn_chan, done_chan)
+	go hub.Run()
+	first, second := hub.SubscribeConn(), hub.SubscribeConn()
+
+	conn_chan <- Connecting
+	conn_chan <- Connected
+	for _, sub := range []<-chan ConnState{first, second} {
+		for state := range sub {
+			if state == Connected {
+				break
+			}
+		}
+	}
+	if hub.Conn() != Connected {
+		t.Fatalf("unexpected connection state %s", hub.Conn())
+	}
+	if NewStateHub(nil, nil, done_chan).SubscribeConn() != nil {
+		t.Fatal("subscription without a connection")
+	}
+}
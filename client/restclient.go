@@ -0,0 +1,386 @@
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const restApiPath = "/api/v1/"
+
+// client for the volumio rest api, it does not need to run on the player
+type RestClient struct {
+	URI       string // base address of volumio, e.g. http://volumio.local
+	http      *http.Client
+	State     State
+	mu        sync.Mutex   // guards State, read by commands from other goroutines
+	Current   func() State // position SeekBy starts from, the last polled state unless set
+	Wait      *sync.WaitGroup
+	InfoLog   *log.Logger
+	ErrorLog  *log.Logger
+	StateChan chan State
+	DoneChan  chan bool
+	closed    chan bool // closed by Close
+	closeOnce sync.Once
+}
+
+func NewRestClient(uri string, wg *sync.WaitGroup, done_chan chan bool, info_log *log.Logger, error_log *log.Logger) *RestClient {
+	state_chan := make(chan State)
+	rest_client := RestClient{
+		URI:       strings.TrimSuffix(uri, "/"),
+		http:      &http.Client{Timeout: 5 * time.Second},
+		Wait:      wg,
+		StateChan: state_chan,
+		DoneChan:  done_chan,
+		closed:    make(chan bool),
+		InfoLog:   info_log,
+		ErrorLog:  error_log,
+	}
+	rest_client.Current = rest_client.currentState
+	return &rest_client
+}
+
+// issue a GET request against an api endpoint and return the body
+func (c *RestClient) get(ctx context.Context, endpoint cmd_rest, query url.Values) ([]byte, error) {
+	uri := c.URI + restApiPath + endpoint.String()
+	if len(query) > 0 {
+		uri += "?" + query.Encode()
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, uri, nil)
+	if err != nil {
+		return nil, cmdError(endpoint, ErrInvalidArgument, err)
+	}
+	return c.do(endpoint, req)
+}
+
+// issue a POST request with a json body against an api endpoint and return the body
+func (c *RestClient) post(ctx context.Context, endpoint cmd_rest, payload interface{}) ([]byte, error) {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return nil, cmdError(endpoint, ErrInvalidArgument, err)
+	}
+	uri := c.URI + restApiPath + endpoint.String()
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, uri, bytes.NewReader(data))
+	if err != nil {
+		return nil, cmdError(endpoint, ErrInvalidArgument, err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	return c.do(endpoint, req)
+}
+
+func (c *RestClient) do(endpoint cmd_rest, req *http.Request) ([]byte, error) {
+	select {
+	case <-c.closed:
+		return nil, cmdError(endpoint, ErrUnavailable, errClosed)
+	default:
+	}
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return nil, cmdError(endpoint, ErrUnavailable, err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, cmdError(endpoint, ErrUnavailable, err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, cmdError(endpoint, ErrBadResponse, fmt.Errorf("%s: %s", resp.Status, bytes.TrimSpace(body)))
+	}
+
+	return body, nil
+}
+
+// run a command, args are added as query parameters in pairs of key and value
+func (c *RestClient) command(ctx context.Context, cmd cmd_rest, args ...string) error {
+	query := url.Values{"cmd": {cmd.String()}}
+	for i := 0; i+1 < len(args); i += 2 {
+		query.Set(args[i], args[i+1])
+	}
+	body, err := c.get(ctx, COMMANDS_R, query)
+	if err != nil {
+		return err
+	}
+
+	var resp CmdResponse
+	if err := json.Unmarshal(body, &resp); err != nil {
+		return cmdError(cmd, ErrBadResponse, err)
+	}
+	return nil
+}
+
+// the rest api is stateless, there is nothing to connect
+func (c *RestClient) Connect() {}
+
+// DoneChan belongs to the caller and is left open
+func (c *RestClient) Close() {
+	c.closeOnce.Do(func() {
+		close(c.closed)
+	})
+	c.http.CloseIdleConnections()
+}
+
+func (c *RestClient) Play(ctx context.Context) error {
+	return c.command(ctx, PLAY_R)
+}
+
+func (c *RestClient) Stop(ctx context.Context) error {
+	return c.command(ctx, STOP_R)
+}
+
+func (c *RestClient) Pause(ctx context.Context) error {
+	return c.command(ctx, PAUSE_R)
+}
+
+func (c *RestClient) Next(ctx context.Context) error {
+	return c.command(ctx, NEXT_R)
+}
+
+func (c *RestClient) Prev(ctx context.Context) error {
+	return c.command(ctx, PREV_R)
+}
+
+func (c *RestClient) Mute(ctx context.Context) error {
+	return c.command(ctx, VOLUME_R, "volume", MUTE_R.String())
+}
+
+func (c *RestClient) UnMute(ctx context.Context) error {
+	return c.command(ctx, VOLUME_R, "volume", UNMUTE_R.String())
+}
+
+func (c *RestClient) SetVolume(ctx context.Context, volume int, mute bool) error {
+	if err := validateVolume(VOLUME_R, volume); err != nil {
+		return err
+	}
+	if mute {
+		if err := c.Mute(ctx); err != nil {
+			return err
+		}
+	} else {
+		if err := c.UnMute(ctx); err != nil {
+			return err
+		}
+	}
+	return c.command(ctx, VOLUME_R, "volume", strconv.Itoa(volume))
+}
+
+// seek to an absolute position in seconds
+func (c *RestClient) Seek(ctx context.Context, position time.Duration) error {
+	if err := validatePosition(SEEK_R, position); err != nil {
+		return err
+	}
+	return c.command(ctx, SEEK_R, "position", strconv.Itoa(int(position.Seconds())))
+}
+
+// seek relative to the current position
+func (c *RestClient) SeekBy(ctx context.Context, delta time.Duration) error {
+	return c.Seek(ctx, c.Current().SeekTarget(delta))
+}
+
+func (c *RestClient) SetRandom(ctx context.Context, enabled bool) error {
+	return c.command(ctx, SETRANDOM_R, "value", strconv.FormatBool(enabled))
+}
+
+func (c *RestClient) SetRepeat(ctx context.Context, enabled bool) error {
+	return c.command(ctx, SETREPEAT_R, "value", strconv.FormatBool(enabled), "repeatSingle", "false")
+}
+
+// repeating a single item implies repeat, turning it off keeps repeat as is
+func (c *RestClient) SetRepeatSingle(ctx context.Context, enabled bool) error {
+	repeat := c.currentState().Repeat || enabled
+	return c.command(ctx, SETREPEAT_R, "value", strconv.FormatBool(repeat), "repeatSingle", strconv.FormatBool(enabled))
+}
+
+// get the state and send it on StateChan if it changed
+func (c *RestClient) GetState(ctx context.Context) error {
+	body, err := c.get(ctx, GETSTATE_R, nil)
+	if err != nil {
+		return err
+	}
+
+	var current_state State
+	if err := json.Unmarshal(body, &current_state); err != nil {
+		return cmdError(GETSTATE_R, ErrBadResponse, err)
+	}
+	if current_state != c.currentState() {
+		select {
+		case c.StateChan <- current_state:
+			c.mu.Lock()
+			c.State = current_state
+			c.mu.Unlock()
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-c.closed:
+			return cmdError(GETSTATE_R, ErrUnavailable, errClosed)
+		}
+	}
+	return nil
+}
+
+// copy of the last polled state
+func (c *RestClient) currentState() State {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.State
+}
+
+// queue, the rest api can't remove or move single items
+func (c *RestClient) GetQueue(ctx context.Context) (Queue, error) {
+	body, err := c.get(ctx, GETQUEUE_R, nil)
+	if err != nil {
+		return nil, err
+	}
+	var resp struct {
+		Queue Queue `json:"queue"`
+	}
+	if err := decodeReply(GETQUEUE_R, body, &resp); err != nil {
+		return nil, err
+	}
+	return resp.Queue, nil
+}
+
+func (c *RestClient) AddToQueue(ctx context.Context, items ...QueueItem) error {
+	if len(items) == 0 {
+		return cmdError(ADDQUEUE_R, ErrInvalidArgument, errors.New("no items"))
+	}
+	_, err := c.post(ctx, ADDQUEUE_R, items)
+	return err
+}
+
+func (c *RestClient) RemoveFromQueue(ctx context.Context, index int) error {
+	return cmdError(REMQUEUE, ErrUnsupported, nil)
+}
+
+func (c *RestClient) MoveQueue(ctx context.Context, from int, to int) error {
+	return cmdError(MOVEQUEUE, ErrUnsupported, nil)
+}
+
+func (c *RestClient) ClearQueue(ctx context.Context) error {
+	return c.command(ctx, CLRQUEUE_R)
+}
+
+// play the item at index in the queue
+func (c *RestClient) PlayIndex(ctx context.Context, index int) error {
+	if err := validateIndex(PLAY_R, index); err != nil {
+		return err
+	}
+	return c.command(ctx, PLAY_R, "N", strconv.Itoa(index))
+}
+
+// the rest api has no addPlay, add the item and play the new last index
+func (c *RestClient) AddPlay(ctx context.Context, item QueueItem) error {
+	queue, err := c.GetQueue(ctx)
+	if err != nil {
+		return err
+	}
+	if err := c.AddToQueue(ctx, item); err != nil {
+		return err
+	}
+	return c.PlayIndex(ctx, len(queue))
+}
+
+func (c *RestClient) ReplaceAndPlay(ctx context.Context, item QueueItem) error {
+	_, err := c.post(ctx, REPLPLAY_R, map[string]QueueItem{"item": item})
+	return err
+}
+
+// library, without a uri the api lists the browse sources
+func (c *RestClient) GetBrowseSources(ctx context.Context) ([]BrowseSource, error) {
+	body, err := c.get(ctx, BROWSE_R, nil)
+	if err != nil {
+		return nil, err
+	}
+	var resp struct {
+		Navigation struct {
+			Lists []BrowseSource `json:"lists"`
+		} `json:"navigation"`
+	}
+	if err := decodeReply(BROWSE_R, body, &resp); err != nil {
+		return nil, err
+	}
+	return resp.Navigation.Lists, nil
+}
+
+func (c *RestClient) BrowseLibrary(ctx context.Context, uri string) (BrowseResult, error) {
+	var result BrowseResult
+	body, err := c.get(ctx, BROWSE_R, url.Values{"uri": {uri}})
+	if err != nil {
+		return result, err
+	}
+	err = decodeReply(BROWSE_R, body, &result)
+	return result, err
+}
+
+func (c *RestClient) Search(ctx context.Context, query string) (SearchResult, error) {
+	if strings.TrimSpace(query) == "" {
+		return SearchResult{}, cmdError(SEARCH_R, ErrInvalidArgument, errors.New("empty query"))
+	}
+	body, err := c.get(ctx, SEARCH_R, url.Values{"query": {query}})
+	if err != nil {
+		return SearchResult{}, err
+	}
+	var result BrowseResult
+	if err := decodeReply(SEARCH_R, body, &result); err != nil {
+		return SearchResult{}, err
+	}
+	return NewSearchResult(result), nil
+}
+
+// playlists, the rest api can only list and play them
+func (c *RestClient) ListPlaylists(ctx context.Context) ([]Playlist, error) {
+	body, err := c.get(ctx, LISTPL_R, nil)
+	if err != nil {
+		return nil, err
+	}
+	var playlists []Playlist
+	if err := decodeReply(LISTPL_R, body, &playlists); err != nil {
+		return nil, err
+	}
+	return playlists, nil
+}
+
+func (c *RestClient) CreatePlaylist(ctx context.Context, name string) error {
+	return cmdError(CREATEPL, ErrUnsupported, nil)
+}
+
+func (c *RestClient) DeletePlaylist(ctx context.Context, name string) error {
+	return cmdError(DELETEPL, ErrUnsupported, nil)
+}
+
+func (c *RestClient) AddToPlaylist(ctx context.Context, name string, item QueueItem) error {
+	return cmdError(ADDPL, ErrUnsupported, nil)
+}
+
+func (c *RestClient) RemoveFromPlaylist(ctx context.Context, name string, item QueueItem) error {
+	return cmdError(REMPL, ErrUnsupported, nil)
+}
+
+func (c *RestClient) PlayPlaylist(ctx context.Context, name string) error {
+	if err := validateName(PLAYPL_R, name); err != nil {
+		return err
+	}
+	return c.command(ctx, PLAYPL_R, "name", name)
+}
+
+// favourites are not exposed by the rest api
+func (c *RestClient) AddToFavourites(ctx context.Context, item QueueItem) error {
+	return cmdError(ADDFAV, ErrUnsupported, nil)
+}
+
+func (c *RestClient) RemoveFromFavourites(ctx context.Context, item QueueItem) error {
+	return cmdError(REMFAV, ErrUnsupported, nil)
+}
+
+func (c *RestClient) PlayFavourites(ctx context.Context) error {
+	return cmdError(PLAYFAV, ErrUnsupported, nil)
+}
@@ -0,0 +1,309 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+	"testing"
+	"time"
+)
+
+// volumio rest api stand-in recording the commands it receives
+type fakeRestApi struct {
+	mu        sync.Mutex
+	state     State
+	queue     Queue
+	sources   []BrowseSource
+	pages     map[string]BrowseResult
+	playlists []string
+	commands  []url.Values
+}
+
+func (f *fakeRestApi) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	switch r.URL.Path {
+	case restApiPath + GETSTATE_R.String():
+		json.NewEncoder(w).Encode(f.state)
+	case restApiPath + GETQUEUE_R.String():
+		json.NewEncoder(w).Encode(map[string]Queue{"queue": f.queue})
+	case restApiPath + ADDQUEUE_R.String():
+		var items Queue
+		if err := json.NewDecoder(r.Body).Decode(&items); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		f.queue = append(f.queue, items...)
+		json.NewEncoder(w).Encode(CmdResponse{Time: 1, Response: "success"})
+	case restApiPath + BROWSE_R.String():
+		uri := r.URL.Query().Get("uri")
+		if uri == "" {
+			json.NewEncoder(w).Encode(map[string]interface{}{
+				"navigation": map[string]interface{}{"lists": f.sources},
+			})
+			return
+		}
+		page, ok := f.pages[uri]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		json.NewEncoder(w).Encode(page)
+	case restApiPath + SEARCH_R.String():
+		page, ok := f.pages["search:"+r.URL.Query().Get("query")]
+		if !ok {
+			page = BrowseResult{}
+		}
+		json.NewEncoder(w).Encode(page)
+	case restApiPath + LISTPL_R.String():
+		json.NewEncoder(w).Encode(f.playlists)
+	case restApiPath + COMMANDS_R.String():
+		f.commands = append(f.commands, r.URL.Query())
+		json.NewEncoder(w).Encode(CmdResponse{Time: 1, Response: r.URL.Query().Get("cmd") + " Success"})
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+func (f *fakeRestApi) lastCommand() url.Values {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if len(f.commands) == 0 {
+		return nil
+	}
+	return f.commands[len(f.commands)-1]
+}
+
+func newTestRestClient(t *testing.T, api *fakeRestApi) *RestClient {
+	t.Helper()
+	server := httptest.NewServer(api)
+	t.Cleanup(server.Close)
+
+	logger := log.New(io.Discard, "", 0)
+	return NewRestClient(server.URL+"/", &sync.WaitGroup{}, make(chan bool), logger, logger)
+}
+
+func Test_RestClientGetState(t *testing.T) {
+	api := fakeRestApi{state: State{Status: "play", Title: "Sleepy Time Time", Seek: 5000, Duration: 300}}
+	c := newTestRestClient(t, &api)
+
+	go c.GetState(context.Background())
+	select {
+	case state := <-c.StateChan:
+		if state != api.state {
+			t.Fatalf("expected %+v, got %+v", api.state, state)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for state")
+	}
+
+	// unchanged state is not sent again
+	done := make(chan bool)
+	go func() {
+		if err := c.GetState(context.Background()); err != nil {
+			t.Error(err)
+		}
+		close(done)
+	}()
+	select {
+	case state := <-c.StateChan:
+		t.Fatalf("unexpected state %+v", state)
+	case <-done:
+	}
+}
+
+func Test_RestClientCommands(t *testing.T) {
+	api := fakeRestApi{}
+	c := newTestRestClient(t, &api)
+	c.State = State{Seek: 30000, Duration: 300}
+
+	tests := []struct {
+		name string
+		call func(context.Context) error
+		want url.Values
+	}{
+		{"play", c.Play, url.Values{"cmd": {"play"}}},
+		{"pause", c.Pause, url.Values{"cmd": {"pause"}}},
+		{"next", c.Next, url.Values{"cmd": {"next"}}},
+		{"volume", func(ctx context.Context) error { return c.SetVolume(ctx, 42, false) }, url.Values{"cmd": {"volume"}, "volume": {"42"}}},
+		{"seek", func(ctx context.Context) error { return c.Seek(ctx, 90*time.Second) }, url.Values{"cmd": {"seek"}, "position": {"90"}}},
+		{"seek by", func(ctx context.Context) error { return c.SeekBy(ctx, -10*time.Second) }, url.Values{"cmd": {"seek"}, "position": {"20"}}},
+		{"random", func(ctx context.Context) error { return c.SetRandom(ctx, true) }, url.Values{"cmd": {"random"}, "value": {"true"}}},
+		{"repeat single", func(ctx context.Context) error { return c.SetRepeatSingle(ctx, true) }, url.Values{"cmd": {"repeat"}, "value": {"true"}, "repeatSingle": {"true"}}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if err := test.call(context.Background()); err != nil {
+				t.Fatal(err)
+			}
+			got := api.lastCommand()
+			if got.Encode() != test.want.Encode() {
+				t.Fatalf("expected %s, got %s", test.want.Encode(), got.Encode())
+			}
+		})
+	}
+}
+
+func Test_RestClientErrors(t *testing.T) {
+	api := fakeRestApi{}
+	c := newTestRestClient(t, &api)
+	ctx := context.Background()
+
+	if err := c.SetVolume(ctx, 150, false); !errors.Is(err, ErrInvalidArgument) {
+		t.Errorf("expected ErrInvalidArgument, got %v", err)
+	}
+	if cmd := api.lastCommand(); cmd != nil {
+		t.Errorf("invalid volume was sent: %s", cmd.Encode())
+	}
+
+	broken := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("<html>not json</html>"))
+	}))
+	defer broken.Close()
+	c.URI = broken.URL
+	if err := c.GetState(ctx); !errors.Is(err, ErrBadResponse) {
+		t.Errorf("expected ErrBadResponse, got %v", err)
+	}
+
+	broken.Close()
+	if err := c.Play(ctx); !errors.Is(err, ErrUnavailable) {
+		t.Errorf("expected ErrUnavailable, got %v", err)
+	}
+}
+
+func Test_RestClientQueue(t *testing.T) {
+	api := fakeRestApi{queue: Queue{{Uri: "mnt/USB/cream/01.flac", Service: "mpd", Name: "I Feel Free"}}}
+	c := newTestRestClient(t, &api)
+	ctx := context.Background()
+
+	item := QueueItem{Uri: "mnt/USB/cream/02.flac", Service: "mpd", Name: "N.S.U."}
+	if err := c.AddToQueue(ctx, item); err != nil {
+		t.Fatal(err)
+	}
+	queue, err := c.GetQueue(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(queue) != 2 || queue[1] != item {
+		t.Fatalf("unexpected queue %+v", queue)
+	}
+
+	if err := c.PlayIndex(ctx, 1); err != nil {
+		t.Fatal(err)
+	}
+	if got := api.lastCommand().Encode(); got != "N=1&cmd=play" {
+		t.Fatalf("unexpected command %s", got)
+	}
+	if err := c.MoveQueue(ctx, 0, 1); !errors.Is(err, ErrUnsupported) {
+		t.Fatalf("expected ErrUnsupported, got %v", err)
+	}
+}
+
+func Test_RestClientBrowse(t *testing.T) {
+	songs := BrowseList{Items: []BrowseItem{{Service: "mpd", Type: "song", Title: "Rollin' and Tumblin'", Uri: "music-library/USB/cream/03.flac"}}}
+	api := fakeRestApi{
+		sources: []BrowseSource{{Name: "Music Library", Uri: "music-library"}, {Name: "Web Radio", Uri: "radio"}},
+		pages: map[string]BrowseResult{
+			"music-library/USB/cream": {Navigation: BrowseNavigation{Prev: BrowseLink{Uri: "music-library/USB"}, Lists: []BrowseList{songs}}},
+		},
+	}
+	c := newTestRestClient(t, &api)
+	ctx := context.Background()
+
+	sources, err := c.GetBrowseSources(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(sources) != 2 || sources[1].Uri != "radio" {
+		t.Fatalf("unexpected sources %+v", sources)
+	}
+
+	page, err := c.BrowseLibrary(ctx, "music-library/USB/cream")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if page.Navigation.Prev.Uri != "music-library/USB" || len(page.Navigation.Lists) != 1 {
+		t.Fatalf("unexpected page %+v", page)
+	}
+	if item := page.Navigation.Lists[0].Items[0]; !item.Playable() || item.QueueItem().Name != item.Title {
+		t.Fatalf("unexpected item %+v", item)
+	}
+
+	if _, err := c.BrowseLibrary(ctx, "music-library/missing"); !errors.Is(err, ErrBadResponse) {
+		t.Fatalf("expected ErrBadResponse, got %v", err)
+	}
+}
+
+func Test_RestClientSearch(t *testing.T) {
+	api := fakeRestApi{
+		pages: map[string]BrowseResult{
+			"search:cream": {Navigation: BrowseNavigation{Lists: []BrowseList{
+				{Title: "Artists", Items: []BrowseItem{{Type: "folder", Title: "Cream"}}},
+				{Title: "Tracks", Items: []BrowseItem{{Type: "song", Title: "Crossroads"}, {Type: "song", Title: "White Room"}}},
+				{Title: "Webradio", Items: []BrowseItem{{Type: "webradio", Title: "Cream FM"}}},
+			}}},
+		},
+	}
+	c := newTestRestClient(t, &api)
+
+	result, err := c.Search(context.Background(), "cream")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(result.Artists) != 1 || len(result.Tracks) != 2 || len(result.Webradio) != 1 || len(result.Albums) != 0 {
+		t.Fatalf("unexpected grouping %+v", result)
+	}
+	if lists := result.Lists(); len(lists) != 3 || lists[1].Title != "Tracks" {
+		t.Fatalf("unexpected lists %+v", lists)
+	}
+	if _, err := c.Search(context.Background(), "  "); !errors.Is(err, ErrInvalidArgument) {
+		t.Fatalf("expected ErrInvalidArgument, got %v", err)
+	}
+}
+
+func Test_RestClientPlaylists(t *testing.T) {
+	api := fakeRestApi{playlists: []string{"cream", "blind faith"}}
+	c := newTestRestClient(t, &api)
+	ctx := context.Background()
+
+	playlists, err := c.ListPlaylists(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(playlists) != 2 || playlists[1].Name != "blind faith" {
+		t.Fatalf("unexpected playlists %+v", playlists)
+	}
+	if err := c.PlayPlaylist(ctx, "blind faith"); err != nil {
+		t.Fatal(err)
+	}
+	if got := api.lastCommand().Encode(); got != "cmd=playplaylist&name=blind+faith" {
+		t.Fatalf("unexpected command %s", got)
+	}
+	if err := c.PlayPlaylist(ctx, ""); !errors.Is(err, ErrInvalidArgument) {
+		t.Fatalf("expected ErrInvalidArgument, got %v", err)
+	}
+	if err := c.AddToFavourites(ctx, QueueItem{Uri: "mnt/USB/cream/01.flac"}); !errors.Is(err, ErrUnsupported) {
+		t.Fatalf("expected ErrUnsupported, got %v", err)
+	}
+}
+
+func Test_RestClientClose(t *testing.T) {
+	api := fakeRestApi{}
+	c := newTestRestClient(t, &api)
+
+	c.Close()
+	c.Close()
+	if err := c.Play(context.Background()); !errors.Is(err, ErrUnavailable) {
+		t.Fatalf("expected ErrUnavailable, got %v", err)
+	}
+	if cmd := api.lastCommand(); cmd != nil {
+		t.Fatalf("command was sent after Close: %s", cmd.Encode())
+	}
+}
@@ -1,112 +1,534 @@
 package client
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
+	"strings"
 	"sync"
+	"time"
 
 	socketio "github.com/googollee/go-socket.io"
 )
 
+const (
+	minReconnectDelay = 500 * time.Millisecond
+	maxReconnectDelay = 30 * time.Second
+)
+
 type SockClient struct {
-	URI       string
-	client    *socketio.Client
-	State     State
-	Wait      *sync.WaitGroup
-	StateChan chan State
-	DoneChan  chan bool
+	URI        string
+	client     *socketio.Client
+	mu         sync.Mutex // guards State, connected and waiters
+	requestMu  sync.Mutex // one request at a time, browse and search share a reply
+	State      State
+	Current    func() State // position SeekBy starts from, the last pushed state unless set
+	connected  bool
+	waiters    map[reply][]chan json.RawMessage // requests waiting for a reply
+	MinBackoff time.Duration                    // delay before the first reconnection attempt
+	MaxBackoff time.Duration                    // upper bound of the reconnection delay
+	Wait       *sync.WaitGroup
+	InfoLog    *log.Logger
+	ErrorLog   *log.Logger
+	StateChan  chan State
+	ConnChan   chan ConnState // connection state transitions
+	DoneChan   chan bool
+	closeOnce  sync.Once
+	lostChan   chan bool // signaled when an established connection drops
 }
 
-func NewClient(uri string, wg *sync.WaitGroup) ClientInterface {
+func NewClient(uri string, wg *sync.WaitGroup, info_log *log.Logger, error_log *log.Logger) (*SockClient, error) {
 	done_chan := make(chan bool)
 	state_chan := make(chan State)
+	conn_chan := make(chan ConnState)
 	client, err := socketio.NewClient(uri, nil)
 	if err != nil {
-		panic(fmt.Sprintf("Could not create socket.io client: %s", err))
+		return nil, fmt.Errorf("could not create socket.io client: %w", err)
 	}
 	vclient := SockClient{
-		URI:       uri,
-		client:    client,
-		Wait:      wg,
-		StateChan: state_chan,
-		DoneChan:  done_chan,
+		URI:        uri,
+		client:     client,
+		MinBackoff: minReconnectDelay,
+		MaxBackoff: maxReconnectDelay,
+		Wait:       wg,
+		InfoLog:    info_log,
+		ErrorLog:   error_log,
+		StateChan:  state_chan,
+		ConnChan:   conn_chan,
+		DoneChan:   done_chan,
+		lostChan:   make(chan bool, 1),
+		waiters:    make(map[reply][]chan json.RawMessage),
 	}
+	vclient.Current = vclient.currentState
+	// volumio pushes the state after every change, subscribe once
+	// and forward the changes instead of polling
+	client.OnEvent(PUSHSTATE.String(), vclient.onPushState)
+	client.OnEvent(PUSHQUEUE.String(), vclient.onReply(PUSHQUEUE))
+	client.OnEvent(PUSHSRCS.String(), vclient.onReply(PUSHSRCS))
+	client.OnEvent(PUSHLIB.String(), vclient.onReply(PUSHLIB))
+	client.OnEvent(PUSHLISTPL.String(), vclient.onReply(PUSHLISTPL))
+	client.OnEvent(PUSHCREATEPL.String(), vclient.onReply(PUSHCREATEPL))
+	client.OnDisconnect(vclient.onDisconnect)
 
-	return &vclient
+	return &vclient, nil
 }
 
+// start the connection supervisor, it keeps reconnecting with backoff
+// until the client is closed and reports transitions on ConnChan
 func (c *SockClient) Connect() {
-	if err := c.client.Connect(); err != nil {
-		panic(err)
-	}
+	go c.supervise()
 }
 
+// DoneChan is closed, it stops the supervisor and pending requests
 func (c *SockClient) Close() {
-	if err := c.client.Close(); err != nil {
-		panic(err)
+	c.closeOnce.Do(func() {
+		close(c.DoneChan)
+	})
+	c.disconnect()
+}
+
+func (c *SockClient) disconnect() {
+	c.mu.Lock()
+	connected := c.connected
+	c.connected = false
+	c.mu.Unlock()
+
+	// closing calls onDisconnect, don't hold the lock
+	if connected {
+		if err := c.client.Close(); err != nil {
+			c.ErrorLog.Printf("error closing connection: %s", err)
+		}
 	}
-	close(c.DoneChan)
-	close(c.StateChan)
 }
 
-// basic playback commands
-func (c *SockClient) Play() {
-	c.client.Emit(PLAY.String())
+func (c *SockClient) supervise() {
+	backoff := newBackoff(c.MinBackoff, c.MaxBackoff)
+	for {
+		// drop disconnects left over from earlier connections
+		select {
+		case <-c.lostChan:
+		default:
+		}
+
+		c.setConnState(Connecting)
+		if err := c.client.Connect(); err != nil {
+			c.ErrorLog.Printf("could not connect to %s: %s", c.URI, err)
+		} else {
+			c.mu.Lock()
+			c.connected = true
+			c.mu.Unlock()
+			backoff.reset()
+			c.InfoLog.Printf("connected to %s", c.URI)
+			c.setConnState(Connected)
+			// request the initial state, updates are pushed from now on
+			if err := c.GetState(context.Background()); err != nil {
+				c.ErrorLog.Println(err)
+			}
+
+			select {
+			case <-c.lostChan:
+				c.InfoLog.Printf("lost connection to %s", c.URI)
+			case <-c.DoneChan:
+				// Close may have run before the connection was made
+				c.disconnect()
+				return
+			}
+		}
+
+		c.setConnState(Disconnected)
+		select {
+		case <-time.After(backoff.next()):
+		case <-c.DoneChan:
+			return
+		}
+	}
+}
+
+func (c *SockClient) onDisconnect(s socketio.Conn, reason string) {
+	c.mu.Lock()
+	c.connected = false
+	c.mu.Unlock()
+
+	select {
+	case c.lostChan <- true:
+	default:
+	}
 }
 
-func (c *SockClient) Pause() {
-	c.client.Emit(PAUSE.String())
+func (c *SockClient) setConnState(state ConnState) {
+	select {
+	case c.ConnChan <- state:
+	case <-c.DoneChan:
+	}
 }
 
-func (c *SockClient) Stop() {
-	c.client.Emit(STOP.String())
+// emit an event if connected, commands issued while disconnected fail
+// with ErrUnavailable
+func (c *SockClient) emit(ctx context.Context, event cmd_sock, args ...interface{}) error {
+	if err := ctx.Err(); err != nil {
+		return cmdError(event, ErrUnavailable, err)
+	}
+	select {
+	case <-c.DoneChan:
+		return cmdError(event, ErrUnavailable, errClosed)
+	default:
+	}
+
+	c.mu.Lock()
+	connected := c.connected
+	c.mu.Unlock()
+
+	if !connected {
+		return cmdError(event, ErrUnavailable, errors.New("not connected"))
+	}
+	c.client.Emit(event.String(), args...)
+	return nil
 }
 
-func (c *SockClient) Next() {
-	c.client.Emit(NEXT.String())
+// emit an event and wait for the reply volumio sends back
+func (c *SockClient) request(ctx context.Context, event cmd_sock, answer reply, args ...interface{}) (json.RawMessage, error) {
+	c.requestMu.Lock()
+	defer c.requestMu.Unlock()
+
+	wait := make(chan json.RawMessage, 1)
+	c.mu.Lock()
+	c.waiters[answer] = append(c.waiters[answer], wait)
+	c.mu.Unlock()
+	defer c.removeWaiter(answer, wait)
+
+	if err := c.emit(ctx, event, args...); err != nil {
+		return nil, err
+	}
+	select {
+	case data := <-wait:
+		return data, nil
+	case <-ctx.Done():
+		return nil, cmdError(event, ErrUnavailable, ctx.Err())
+	case <-c.DoneChan:
+		return nil, cmdError(event, ErrUnavailable, errClosed)
+	}
 }
 
-func (c *SockClient) Prev() {
-	c.client.Emit(PREV.String())
+func (c *SockClient) removeWaiter(answer reply, wait chan json.RawMessage) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	waiters := c.waiters[answer]
+	for i := range waiters {
+		if waiters[i] == wait {
+			c.waiters[answer] = append(waiters[:i], waiters[i+1:]...)
+			return
+		}
+	}
 }
 
-// get state
-func (c *SockClient) GetState() {
-	c.client.Emit(GETSTATE.String())
-	c.client.OnEvent(PUSHSTATE.String(), func(s socketio.Conn, data string) {
-		if err := json.Unmarshal([]byte(data), &c.State); err != nil {
-			log.Printf("Error processing state: %s", err)
+// handler passing a reply to all requests waiting for it
+func (c *SockClient) onReply(answer reply) func(socketio.Conn, json.RawMessage) {
+	return func(s socketio.Conn, data json.RawMessage) {
+		c.mu.Lock()
+		defer c.mu.Unlock()
+		for _, wait := range c.waiters[answer] {
+			select {
+			case wait <- data:
+			default:
+			}
 		}
-	})
+	}
+}
+
+// state changes are pushed, the client does not need to be polled
+func (c *SockClient) PushesState() bool {
+	return true
+}
+
+// decoding is done here, a payload the socket.io parser can't decode
+// into the handler's argument would drop the connection
+func (c *SockClient) onPushState(s socketio.Conn, data json.RawMessage) {
+	var state State
+	if err := decodeReply(PUSHSTATE, data, &state); err != nil {
+		c.ErrorLog.Println(err)
+		return
+	}
+
+	c.mu.Lock()
+	changed := state != c.State
+	c.State = state
+	c.mu.Unlock()
+
+	if changed {
+		select {
+		case c.StateChan <- state:
+		case <-c.DoneChan:
+		}
+	}
+}
+
+// copy of the last pushed state
+func (c *SockClient) currentState() State {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.State
+}
+
+// basic playback commands
+func (c *SockClient) Play(ctx context.Context) error {
+	return c.emit(ctx, PLAY)
+}
+
+func (c *SockClient) Pause(ctx context.Context) error {
+	return c.emit(ctx, PAUSE)
+}
+
+func (c *SockClient) Stop(ctx context.Context) error {
+	return c.emit(ctx, STOP)
+}
+
+func (c *SockClient) Next(ctx context.Context) error {
+	return c.emit(ctx, NEXT)
+}
+
+func (c *SockClient) Prev(ctx context.Context) error {
+	return c.emit(ctx, PREV)
+}
+
+// seek to an absolute position in seconds
+func (c *SockClient) Seek(ctx context.Context, position time.Duration) error {
+	if err := validatePosition(SEEK, position); err != nil {
+		return err
+	}
+	return c.emit(ctx, SEEK, int(position.Seconds()))
+}
+
+// seek relative to the current position, volumio only pushes changes so
+// the pushed position is stale while playing unless Current interpolates it
+func (c *SockClient) SeekBy(ctx context.Context, delta time.Duration) error {
+	return c.Seek(ctx, c.Current().SeekTarget(delta))
+}
+
+// shuffle and repeat
+func (c *SockClient) SetRandom(ctx context.Context, enabled bool) error {
+	return c.emit(ctx, SETRANDOM, map[string]interface{}{"value": enabled})
+}
+
+func (c *SockClient) SetRepeat(ctx context.Context, enabled bool) error {
+	return c.emit(ctx, SETREPEAT, map[string]interface{}{"value": enabled, "repeatSingle": false})
+}
+
+// repeating a single item implies repeat, turning it off keeps repeat as is
+func (c *SockClient) SetRepeatSingle(ctx context.Context, enabled bool) error {
+	repeat := c.currentState().Repeat || enabled
+	return c.emit(ctx, SETREPEAT, map[string]interface{}{"value": repeat, "repeatSingle": enabled})
+}
+
+// request a state push, the reply is handled by onPushState
+func (c *SockClient) GetState(ctx context.Context) error {
+	return c.emit(ctx, GETSTATE)
 }
 
 // mute
-func (c *SockClient) Mute() {
-	c.client.Emit(MUTE.String())
+func (c *SockClient) Mute(ctx context.Context) error {
+	return c.emit(ctx, MUTE)
 }
 
-func (c *SockClient) UnMute() {
-	c.client.Emit(UNMUTE.String())
+func (c *SockClient) UnMute(ctx context.Context) error {
+	return c.emit(ctx, UNMUTE)
 }
 
 // set volume
-func (c *SockClient) SetVolume(volume int, mute bool) {
+func (c *SockClient) SetVolume(ctx context.Context, volume int, mute bool) error {
+	if err := validateVolume(VOLUME, volume); err != nil {
+		return err
+	}
 	var args interface{}
 	if mute {
 		args = mute
 	} else {
 		args = volume
 	}
-	c.client.Emit(VOLUME.String(), args)
+	return c.emit(ctx, VOLUME, args)
 }
 
-// handle presets
-//   TODO:
-//   these functions are intended to be used with
-//   preset button, where a long-press saves the
-//   current playback to that button.
-//   - extract current playback with GetState
-//   - create and save a playlist entry
-//   - play playlist
-//   NOTE: maybe addPlayCue could be used instead
+// queue
+func (c *SockClient) GetQueue(ctx context.Context) (Queue, error) {
+	data, err := c.request(ctx, GETQUEUE, PUSHQUEUE)
+	if err != nil {
+		return nil, err
+	}
+	var queue Queue
+	if err := decodeReply(PUSHQUEUE, data, &queue); err != nil {
+		return nil, err
+	}
+	return queue, nil
+}
+
+func (c *SockClient) AddToQueue(ctx context.Context, items ...QueueItem) error {
+	if len(items) == 0 {
+		return cmdError(ADDQUEUE, ErrInvalidArgument, errors.New("no items"))
+	}
+	return c.emit(ctx, ADDQUEUE, items)
+}
+
+func (c *SockClient) RemoveFromQueue(ctx context.Context, index int) error {
+	if err := validateIndex(REMQUEUE, index); err != nil {
+		return err
+	}
+	return c.emit(ctx, REMQUEUE, map[string]interface{}{"value": index})
+}
+
+func (c *SockClient) MoveQueue(ctx context.Context, from int, to int) error {
+	if err := validateIndex(MOVEQUEUE, from); err != nil {
+		return err
+	}
+	if err := validateIndex(MOVEQUEUE, to); err != nil {
+		return err
+	}
+	return c.emit(ctx, MOVEQUEUE, map[string]interface{}{"from": from, "to": to})
+}
+
+func (c *SockClient) ClearQueue(ctx context.Context) error {
+	return c.emit(ctx, CLRQUEUE)
+}
+
+// play the item at index in the queue
+func (c *SockClient) PlayIndex(ctx context.Context, index int) error {
+	if err := validateIndex(PLAY, index); err != nil {
+		return err
+	}
+	return c.emit(ctx, PLAY, map[string]interface{}{"value": index})
+}
+
+func (c *SockClient) AddPlay(ctx context.Context, item QueueItem) error {
+	return c.emit(ctx, ADDPLAY, item)
+}
+
+func (c *SockClient) ReplaceAndPlay(ctx context.Context, item QueueItem) error {
+	return c.emit(ctx, REPLPLAY, item)
+}
+
+// library
+func (c *SockClient) GetBrowseSources(ctx context.Context) ([]BrowseSource, error) {
+	data, err := c.request(ctx, GETSRCS, PUSHSRCS)
+	if err != nil {
+		return nil, err
+	}
+	var sources []BrowseSource
+	if err := decodeReply(PUSHSRCS, data, &sources); err != nil {
+		return nil, err
+	}
+	return sources, nil
+}
+
+func (c *SockClient) BrowseLibrary(ctx context.Context, uri string) (BrowseResult, error) {
+	var result BrowseResult
+	data, err := c.request(ctx, BROWSE, PUSHLIB, map[string]interface{}{"uri": uri})
+	if err != nil {
+		return result, err
+	}
+	err = decodeReply(PUSHLIB, data, &result)
+	return result, err
+}
+
+// search replies like browseLibrary with a list per kind of result
+func (c *SockClient) Search(ctx context.Context, query string) (SearchResult, error) {
+	if strings.TrimSpace(query) == "" {
+		return SearchResult{}, cmdError(SEARCH, ErrInvalidArgument, errors.New("empty query"))
+	}
+	data, err := c.request(ctx, SEARCH, PUSHLIB, map[string]interface{}{"value": query})
+	if err != nil {
+		return SearchResult{}, err
+	}
+	var result BrowseResult
+	if err := decodeReply(PUSHLIB, data, &result); err != nil {
+		return SearchResult{}, err
+	}
+	return NewSearchResult(result), nil
+}
+
+// playlists
+func (c *SockClient) ListPlaylists(ctx context.Context) ([]Playlist, error) {
+	data, err := c.request(ctx, LISTPL, PUSHLISTPL)
+	if err != nil {
+		return nil, err
+	}
+	var playlists []Playlist
+	if err := decodeReply(PUSHLISTPL, data, &playlists); err != nil {
+		return nil, err
+	}
+	return playlists, nil
+}
+
+// volumio answers with success and a reason, e.g. when the name is taken
+func (c *SockClient) CreatePlaylist(ctx context.Context, name string) error {
+	if err := validateName(CREATEPL, name); err != nil {
+		return err
+	}
+	data, err := c.request(ctx, CREATEPL, PUSHCREATEPL, map[string]interface{}{"name": name})
+	if err != nil {
+		return err
+	}
+	var resp struct {
+		Success bool   `json:"success"`
+		Reason  string `json:"reason"`
+	}
+	if err := decodeReply(PUSHCREATEPL, data, &resp); err != nil {
+		return err
+	}
+	if !resp.Success {
+		return cmdError(CREATEPL, ErrBadResponse, errors.New(resp.Reason))
+	}
+	return nil
+}
+
+func (c *SockClient) DeletePlaylist(ctx context.Context, name string) error {
+	if err := validateName(DELETEPL, name); err != nil {
+		return err
+	}
+	return c.emit(ctx, DELETEPL, map[string]interface{}{"name": name})
+}
+
+func (c *SockClient) AddToPlaylist(ctx context.Context, name string, item QueueItem) error {
+	if err := validateName(ADDPL, name); err != nil {
+		return err
+	}
+	if err := validateItem(ADDPL, item); err != nil {
+		return err
+	}
+	return c.emit(ctx, ADDPL, map[string]interface{}{"name": name, "service": item.Service, "uri": item.Uri})
+}
+
+func (c *SockClient) RemoveFromPlaylist(ctx context.Context, name string, item QueueItem) error {
+	if err := validateName(REMPL, name); err != nil {
+		return err
+	}
+	if err := validateItem(REMPL, item); err != nil {
+		return err
+	}
+	return c.emit(ctx, REMPL, map[string]interface{}{"name": name, "service": item.Service, "uri": item.Uri})
+}
+
+func (c *SockClient) PlayPlaylist(ctx context.Context, name string) error {
+	if err := validateName(PLAYPL, name); err != nil {
+		return err
+	}
+	return c.emit(ctx, PLAYPL, map[string]interface{}{"name": name})
+}
+
+// favourites
+func (c *SockClient) AddToFavourites(ctx context.Context, item QueueItem) error {
+	if err := validateItem(ADDFAV, item); err != nil {
+		return err
+	}
+	return c.emit(ctx, ADDFAV, map[string]interface{}{"service": item.Service, "uri": item.Uri, "title": item.Name})
+}
+
+func (c *SockClient) RemoveFromFavourites(ctx context.Context, item QueueItem) error {
+	if err := validateItem(REMFAV, item); err != nil {
+		return err
+	}
+	return c.emit(ctx, REMFAV, map[string]interface{}{"service": item.Service, "uri": item.Uri})
+}
+
+func (c *SockClient) PlayFavourites(ctx context.Context) error {
+	return c.emit(ctx, PLAYFAV)
+}
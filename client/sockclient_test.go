@@ -0,0 +1,266 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log"
+	"net"
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+
+	socketio "github.com/googollee/go-socket.io"
+)
+
+// local volumio stand-in answering getState with a pushState
+type fakeVolumio struct {
+	server   *socketio.Server
+	http     *http.Server
+	listener net.Listener
+	seeks    chan int // positions of seek requests
+}
+
+func startFakeVolumio(t *testing.T, addr string, state State) *fakeVolumio {
+	t.Helper()
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		t.Fatalf("could not listen on %s: %s", addr, err)
+	}
+	server := socketio.NewServer(nil)
+	server.OnConnect("/", func(s socketio.Conn) error {
+		return nil
+	})
+	server.OnEvent("/", GETSTATE.String(), func(s socketio.Conn) {
+		s.Emit(PUSHSTATE.String(), state)
+	})
+	server.OnEvent("/", GETQUEUE.String(), func(s socketio.Conn) {
+		s.Emit(PUSHQUEUE.String(), Queue{{Name: state.Title, Artist: state.Artist}})
+	})
+	server.OnEvent("/", LISTPL.String(), func(s socketio.Conn) {
+		s.Emit(PUSHLISTPL.String(), []string{"cream"})
+	})
+	server.OnEvent("/", CREATEPL.String(), func(s socketio.Conn, data map[string]interface{}) {
+		if data["name"] == "cream" {
+			s.Emit(PUSHCREATEPL.String(), map[string]interface{}{"success": false, "reason": "Playlist already exists"})
+			return
+		}
+		s.Emit(PUSHCREATEPL.String(), map[string]interface{}{"success": true})
+	})
+	seeks := make(chan int, 1)
+	server.OnEvent("/", SEEK.String(), func(s socketio.Conn, position int) {
+		seeks <- position
+	})
+	go server.Serve()
+
+	mux := http.NewServeMux()
+	mux.Handle("/socket.io/", server)
+	fake := fakeVolumio{
+		server:   server,
+		http:     &http.Server{Handler: mux},
+		listener: listener,
+		seeks:    seeks,
+	}
+	go fake.http.Serve(listener)
+
+	return &fake
+}
+
+func (f *fakeVolumio) stop() {
+	f.server.Close()
+	f.http.Close()
+}
+
+func newTestSockClient(t *testing.T, addr string) *SockClient {
+	t.Helper()
+	logger := log.New(io.Discard, "", 0)
+	c, err := NewClient("http://"+addr, &sync.WaitGroup{}, logger, logger)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return c
+}
+
+func expectConnState(t *testing.T, c *SockClient, want ConnState) {
+	t.Helper()
+	select {
+	case got := <-c.ConnChan:
+		if got != want {
+			t.Fatalf("expected %s, got %s", want, got)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatalf("timed out waiting for %s", want)
+	}
+}
+
+func expectState(t *testing.T, c *SockClient, want State) {
+	t.Helper()
+	select {
+	case got := <-c.StateChan:
+		if got != want {
+			t.Fatalf("expected %+v, got %+v", want, got)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatalf("timed out waiting for state")
+	}
+}
+
+func Test_SockClientReconnect(t *testing.T) {
+	first := State{Status: "play", Title: "Sleepy Time Time", Volume: 80}
+	second := State{Status: "pause", Title: "Sweet Wine", Volume: 80}
+
+	volumio := startFakeVolumio(t, "127.0.0.1:0", first)
+	addr := volumio.listener.Addr().String()
+
+	c := newTestSockClient(t, addr)
+	c.MinBackoff = 10 * time.Millisecond
+	c.MaxBackoff = 50 * time.Millisecond
+	c.Connect()
+	defer c.Close()
+
+	expectConnState(t, c, Connecting)
+	expectConnState(t, c, Connected)
+	expectState(t, c, first)
+
+	volumio.stop()
+	expectConnState(t, c, Disconnected)
+
+	// attempts fail until the server is back
+	expectConnState(t, c, Connecting)
+	expectConnState(t, c, Disconnected)
+
+	volumio = startFakeVolumio(t, addr, second)
+	defer volumio.stop()
+	for {
+		state := <-c.ConnChan
+		if state == Connected {
+			break
+		}
+	}
+	expectState(t, c, second)
+}
+
+func Test_SockClientRequest(t *testing.T) {
+	state := State{Status: "play", Title: "Sleepy Time Time", Artist: "Cream"}
+	volumio := startFakeVolumio(t, "127.0.0.1:0", state)
+	defer volumio.stop()
+
+	c := newTestSockClient(t, volumio.listener.Addr().String())
+	c.Connect()
+	defer c.Close()
+	expectConnState(t, c, Connecting)
+	expectConnState(t, c, Connected)
+	expectState(t, c, state)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	queue, err := c.GetQueue(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(queue) != 1 || queue[0].Name != state.Title || queue[0].Artist != state.Artist {
+		t.Fatalf("unexpected queue %+v", queue)
+	}
+}
+
+// volumio pushes no state while playing, relative seeks start from
+// Current which the hub interpolates
+func Test_SockClientSeekBy(t *testing.T) {
+	state := State{Status: "play", Title: "Sleepy Time Time", Seek: 10000, Duration: 300}
+	volumio := startFakeVolumio(t, "127.0.0.1:0", state)
+	defer volumio.stop()
+
+	c := newTestSockClient(t, volumio.listener.Addr().String())
+	c.Connect()
+	defer c.Close()
+	expectConnState(t, c, Connecting)
+	expectConnState(t, c, Connected)
+	expectState(t, c, state)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	expectSeek := func(want int) {
+		t.Helper()
+		if err := c.SeekBy(ctx, 10*time.Second); err != nil {
+			t.Fatal(err)
+		}
+		select {
+		case position := <-volumio.seeks:
+			if position != want {
+				t.Fatalf("expected a seek to %d, got %d", want, position)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatal("no seek")
+		}
+	}
+	expectSeek(20)
+	c.Current = func() State { return state.Advanced(time.Minute) }
+	expectSeek(80)
+}
+
+func Test_SockClientPlaylists(t *testing.T) {
+	state := State{Status: "play", Title: "Sleepy Time Time"}
+	volumio := startFakeVolumio(t, "127.0.0.1:0", state)
+	defer volumio.stop()
+
+	c := newTestSockClient(t, volumio.listener.Addr().String())
+	c.Connect()
+	defer c.Close()
+	expectConnState(t, c, Connecting)
+	expectConnState(t, c, Connected)
+	expectState(t, c, state)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	playlists, err := c.ListPlaylists(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(playlists) != 1 || playlists[0].Name != "cream" {
+		t.Fatalf("unexpected playlists %+v", playlists)
+	}
+	if err := c.CreatePlaylist(ctx, "blind faith"); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.CreatePlaylist(ctx, "cream"); !errors.Is(err, ErrBadResponse) {
+		t.Fatalf("expected ErrBadResponse, got %v", err)
+	}
+	if err := c.AddToFavourites(ctx, QueueItem{}); !errors.Is(err, ErrInvalidArgument) {
+		t.Fatalf("expected ErrInvalidArgument, got %v", err)
+	}
+}
+
+func Test_Backoff(t *testing.T) {
+	b := newBackoff(100*time.Millisecond, time.Second)
+	limits := []time.Duration{100, 200, 400, 800, 1000, 1000}
+	for i, limit := range limits {
+		limit *= time.Millisecond
+		delay := b.next()
+		if delay < limit/2 || delay > limit {
+			t.Fatalf("attempt %d: delay %s outside [%s, %s]", i, delay, limit/2, limit)
+		}
+	}
+	b.reset()
+	if delay := b.next(); delay > 100*time.Millisecond {
+		t.Fatalf("delay %s not reset", delay)
+	}
+}
+
+func Test_SockClientClose(t *testing.T) {
+	state := State{Status: "play", Title: "Sleepy Time Time"}
+	volumio := startFakeVolumio(t, "127.0.0.1:0", state)
+	defer volumio.stop()
+
+	c := newTestSockClient(t, volumio.listener.Addr().String())
+	c.Connect()
+	expectConnState(t, c, Connecting)
+	expectConnState(t, c, Connected)
+	expectState(t, c, state)
+
+	c.Close()
+	c.Close()
+	if err := c.Play(context.Background()); !errors.Is(err, ErrUnavailable) {
+		t.Fatalf("expected ErrUnavailable, got %v", err)
+	}
+}
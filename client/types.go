@@ -1,24 +1,231 @@
 package client
 
+import (
+	"encoding/json"
+	"strings"
+	"time"
+)
+
 // Volumio 3 types and constants
 
 type State struct {
-	Status     string `json:"status"`     // status is the status of the player
-	Position   int    `json:"position"`   // position is the position in the play queue of current playing track (if any)
-	Title      string `json:"title"`      // title is the item's title
-	Artist     string `json:"artist"`     // artist is the item's artist
-	Album      string `json:"album"`      // album is the item's album
-	AlbumArt   string `json:"albumart"`   // albumart the URL of AlbumArt (via last.fm APIs)
-	Seek       int    `json:"seek"`       // seek is the item's current elapsed time
-	Duration   int    `json:"duration"`   // duration is the item's duration, if any
-	SampleRate string `json:"samplerate"` // samplerate current samplerate
-	BitRate    string `json:"bitrate"`    // bitrate
-	BitDepth   string `json:"bitdepth"`   // bitdepth bitdepth
-	Channels   int    `json:"channels"`   // channels mono or stereo
-	Volume     int    `json:"volume"`     // volume current Volume
-	Mute       bool   `json:"mute"`       // mute if true, Volumio is muted
-	Service    string `json:"service"`    // service current playback service (mpd, spop...)
-	TrackType  string `json:"trackType"`  // item's format
+	Status       string `json:"status"`       // status is the status of the player
+	Position     int    `json:"position"`     // position is the position in the play queue of current playing track (if any)
+	Title        string `json:"title"`        // title is the item's title
+	Artist       string `json:"artist"`       // artist is the item's artist
+	Album        string `json:"album"`        // album is the item's album
+	AlbumArt     string `json:"albumart"`     // albumart the URL of AlbumArt (via last.fm APIs)
+	Seek         int    `json:"seek"`         // seek is the item's current elapsed time
+	Duration     int    `json:"duration"`     // duration is the item's duration, if any
+	SampleRate   string `json:"samplerate"`   // samplerate current samplerate
+	BitRate      string `json:"bitrate"`      // bitrate
+	BitDepth     string `json:"bitdepth"`     // bitdepth bitdepth
+	Channels     int    `json:"channels"`     // channels mono or stereo
+	Volume       int    `json:"volume"`       // volume current Volume
+	Mute         bool   `json:"mute"`         // mute if true, Volumio is muted
+	Service      string `json:"service"`      // service current playback service (mpd, spop...)
+	TrackType    string `json:"trackType"`    // item's format
+	Random       bool   `json:"random"`       // random if true, the queue is shuffled
+	Repeat       bool   `json:"repeat"`       // repeat if true, the queue is repeated
+	RepeatSingle bool   `json:"repeatSingle"` // repeatSingle if true, the current item is repeated
+	Uri          string `json:"uri"`          // uri of the current item
+}
+
+// Elapsed returns the elapsed time of the current item
+func (s State) Elapsed() time.Duration {
+	return time.Duration(s.Seek) * time.Millisecond
+}
+
+// Advanced returns the state with the seek position moved on by elapsed
+// while playing, capped at the duration of the item
+func (s State) Advanced(elapsed time.Duration) State {
+	if s.Status != "play" {
+		return s
+	}
+	s.Seek += int(elapsed.Milliseconds())
+	if limit := s.Duration * 1000; limit > 0 && s.Seek > limit {
+		s.Seek = limit
+	}
+	return s
+}
+
+// Length returns the duration of the current item, zero for streams
+func (s State) Length() time.Duration {
+	return time.Duration(s.Duration) * time.Second
+}
+
+// QueueItem returns the current item for the queue, playlist and favourites commands
+func (s State) QueueItem() QueueItem {
+	return QueueItem{
+		Uri:       s.Uri,
+		Service:   s.Service,
+		Name:      s.Title,
+		Artist:    s.Artist,
+		Album:     s.Album,
+		AlbumArt:  s.AlbumArt,
+		TrackType: s.TrackType,
+		Duration:  s.Duration,
+	}
+}
+
+// SeekTarget returns the absolute position after moving delta away
+// from the elapsed time, clamped to the bounds of the current item
+func (s State) SeekTarget(delta time.Duration) time.Duration {
+	target := s.Elapsed() + delta
+	if target < 0 {
+		target = 0
+	}
+	if s.Duration > 0 && target > s.Length() {
+		target = s.Length()
+	}
+	return target
+}
+
+// item in the play queue
+type QueueItem struct {
+	Uri       string `json:"uri"`       // uri of the item
+	Service   string `json:"service"`   // service playing the item (mpd, webradio...)
+	Name      string `json:"name"`      // name is the item's title
+	Artist    string `json:"artist"`    // artist is the item's artist
+	Album     string `json:"album"`     // album is the item's album
+	AlbumArt  string `json:"albumart"`  // albumart the URL of AlbumArt
+	TrackType string `json:"trackType"` // item's format
+	Duration  int    `json:"duration"`  // duration in seconds, if any
+}
+
+// play queue, State.Position is the index of the current item
+type Queue []QueueItem
+
+// source shown at the root of the library (music library, webradio, plugins...)
+type BrowseSource struct {
+	Name       string `json:"name"`        // name of the source
+	Uri        string `json:"uri"`         // uri to browse the source
+	PluginType string `json:"plugin_type"` // plugin category of the source
+	PluginName string `json:"plugin_name"` // plugin providing the source
+	AlbumArt   string `json:"albumart"`    // icon or albumart of the source
+}
+
+// item of a library list, folders can be browsed further
+type BrowseItem struct {
+	Service  string `json:"service"`  // service playing the item
+	Type     string `json:"type"`     // folder, song, webradio, playlist...
+	Title    string `json:"title"`    // title is the item's title
+	Artist   string `json:"artist"`   // artist is the item's artist
+	Album    string `json:"album"`    // album is the item's album
+	Uri      string `json:"uri"`      // uri to browse or play the item
+	AlbumArt string `json:"albumart"` // albumart the URL of AlbumArt
+}
+
+// item types that are played rather than browsed
+var playableTypes = map[string]bool{
+	"song":       true,
+	"track":      true,
+	"cuesong":    true,
+	"webradio":   true,
+	"mywebradio": true,
+}
+
+// Playable reports whether the item is a single track or station
+func (i BrowseItem) Playable() bool {
+	return playableTypes[i.Type]
+}
+
+// QueueItem converts the item for the queue commands
+func (i BrowseItem) QueueItem() QueueItem {
+	return QueueItem{
+		Uri:      i.Uri,
+		Service:  i.Service,
+		Name:     i.Title,
+		Artist:   i.Artist,
+		Album:    i.Album,
+		AlbumArt: i.AlbumArt,
+	}
+}
+
+// titled list of items, a page can hold several (artists, albums...)
+type BrowseList struct {
+	Title              string       `json:"title"`              // title of the list, may be empty
+	AvailableListViews []string     `json:"availableListViews"` // list or grid
+	Items              []BrowseItem `json:"items"`              // items of the list
+}
+
+type BrowseLink struct {
+	Uri string `json:"uri"`
+}
+
+type BrowseNavigation struct {
+	Prev  BrowseLink   `json:"prev"`  // parent of the page
+	Lists []BrowseList `json:"lists"` // lists on the page
+}
+
+// page of the library as returned by browseLibrary
+type BrowseResult struct {
+	Navigation BrowseNavigation `json:"navigation"`
+}
+
+// stored playlist, volumio lists playlists by name
+type Playlist struct {
+	Name string `json:"name"`
+}
+
+// playlists are sent as plain strings
+func (p *Playlist) UnmarshalJSON(data []byte) error {
+	var name string
+	if err := json.Unmarshal(data, &name); err == nil {
+		p.Name = name
+		return nil
+	}
+	type playlist Playlist
+	return json.Unmarshal(data, (*playlist)(p))
+}
+
+// search results grouped by kind
+type SearchResult struct {
+	Artists  []BrowseItem
+	Albums   []BrowseItem
+	Tracks   []BrowseItem
+	Webradio []BrowseItem
+	Other    []BrowseItem // items of lists that can't be told apart
+}
+
+// group the lists of a search reply, volumio titles the lists by
+// kind and service ("Artists", "Webradio Albums"...)
+func NewSearchResult(result BrowseResult) SearchResult {
+	var search SearchResult
+	for _, list := range result.Navigation.Lists {
+		title := strings.ToLower(list.Title)
+		switch {
+		case strings.Contains(title, "radio"):
+			search.Webradio = append(search.Webradio, list.Items...)
+		case strings.Contains(title, "artist"):
+			search.Artists = append(search.Artists, list.Items...)
+		case strings.Contains(title, "album"):
+			search.Albums = append(search.Albums, list.Items...)
+		case strings.Contains(title, "track"), strings.Contains(title, "song"):
+			search.Tracks = append(search.Tracks, list.Items...)
+		default:
+			search.Other = append(search.Other, list.Items...)
+		}
+	}
+	return search
+}
+
+// Lists returns the non empty groups as titled lists
+func (s SearchResult) Lists() []BrowseList {
+	groups := []BrowseList{
+		{Title: "Artists", Items: s.Artists},
+		{Title: "Albums", Items: s.Albums},
+		{Title: "Tracks", Items: s.Tracks},
+		{Title: "Webradio", Items: s.Webradio},
+		{Title: "Other", Items: s.Other},
+	}
+	var lists []BrowseList
+	for _, group := range groups {
+		if len(group.Items) > 0 {
+			lists = append(lists, group)
+		}
+	}
+	return lists
 }
 
 // constants
@@ -49,6 +256,25 @@ type cmd_sock string
 
 const (
 	GETSTATE  cmd_sock = "getState"
+	GETQUEUE  cmd_sock = "getQueue"
+	ADDQUEUE  cmd_sock = "addToQueue"
+	REMQUEUE  cmd_sock = "removeFromQueue"
+	MOVEQUEUE cmd_sock = "moveQueue"
+	CLRQUEUE  cmd_sock = "clearQueue"
+	ADDPLAY   cmd_sock = "addPlay"
+	REPLPLAY  cmd_sock = "replaceAndPlay"
+	GETSRCS   cmd_sock = "getBrowseSources"
+	BROWSE    cmd_sock = "browseLibrary"
+	SEARCH    cmd_sock = "search"
+	LISTPL    cmd_sock = "listPlaylist"
+	CREATEPL  cmd_sock = "createPlaylist"
+	DELETEPL  cmd_sock = "deletePlaylist"
+	ADDPL     cmd_sock = "addToPlaylist"
+	REMPL     cmd_sock = "removeFromPlaylist"
+	PLAYPL    cmd_sock = "playPlaylist"
+	ADDFAV    cmd_sock = "addToFavourites"
+	REMFAV    cmd_sock = "removeFromFavourites"
+	PLAYFAV   cmd_sock = "playFavourites"
 	PLAY      cmd_sock = "play"
 	PAUSE     cmd_sock = "pause"
 	STOP      cmd_sock = "stop"
@@ -70,9 +296,45 @@ func (c cmd_sock) String() string {
 type reply string
 
 const (
-	PUSHSTATE reply = "pushState"
+	PUSHSTATE    reply = "pushState"
+	PUSHQUEUE    reply = "pushQueue"
+	PUSHSRCS     reply = "pushBrowseSources"
+	PUSHLIB      reply = "pushBrowseLibrary"
+	PUSHLISTPL   reply = "pushListPlaylist"
+	PUSHCREATEPL reply = "pushCreatePlaylist"
 )
 
 func (r reply) String() string {
 	return string(r)
 }
+
+// rest api
+type cmd_rest string
+
+const (
+	GETSTATE_R  cmd_rest = "getState"
+	GETQUEUE_R  cmd_rest = "getQueue"
+	ADDQUEUE_R  cmd_rest = "addToQueue"
+	CLRQUEUE_R  cmd_rest = "clearQueue"
+	REPLPLAY_R  cmd_rest = "replaceAndPlay"
+	BROWSE_R    cmd_rest = "browse"
+	SEARCH_R    cmd_rest = "search"
+	LISTPL_R    cmd_rest = "listplaylists"
+	PLAYPL_R    cmd_rest = "playplaylist"
+	COMMANDS_R  cmd_rest = "commands/"
+	PLAY_R      cmd_rest = "play"
+	PAUSE_R     cmd_rest = "pause"
+	STOP_R      cmd_rest = "stop"
+	NEXT_R      cmd_rest = "next"
+	PREV_R      cmd_rest = "prev"
+	SEEK_R      cmd_rest = "seek"
+	SETRANDOM_R cmd_rest = "random"
+	SETREPEAT_R cmd_rest = "repeat"
+	VOLUME_R    cmd_rest = "volume"
+	MUTE_R      cmd_rest = "mute"
+	UNMUTE_R    cmd_rest = "unmute"
+)
+
+func (c cmd_rest) String() string {
+	return string(c)
+}
@@ -0,0 +1,245 @@
+package config
+
+import (
+	"bytes"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"volumgui/bindings"
+	"volumgui/control"
+	"volumgui/leds"
+	"volumgui/mqtt"
+	"volumgui/presets"
+	"volumgui/ui"
+
+	"gopkg.in/yaml.v3"
+)
+
+const envPrefix = "VOLUMGUI_"
+
+// Config holds the settings of volumgui, each one is taken from the
+// last layer setting it: defaults, the config file, environment
+// variables like VOLUMGUI_MQTT_BROKER and the command line flags
+type Config struct {
+	Backend      string            `yaml:"backend"`       // cmd, socket or rest
+	Host         string            `yaml:"host"`          // volumio address of the socket and rest backends
+	LogFile      string            `yaml:"log_file"`      // file the log is appended to
+	PollInterval time.Duration     `yaml:"poll_interval"` // state polling of backends not pushing their state
+	Presets      string            `yaml:"presets"`
+	Bindings     string            `yaml:"bindings"`
+	Leds         string            `yaml:"leds"`
+	Http         string            `yaml:"http"`    // dashboard address, empty to disable
+	Control      string            `yaml:"control"` // control socket, empty to disable
+	Mpris        bool              `yaml:"mpris"`
+	Mqtt         Mqtt              `yaml:"mqtt"`
+	Ui           ui.Options        `yaml:"ui"`
+	path         string            // config file read, empty without one
+	sources      map[string]string // layer of the settings not left at their default
+}
+
+type Mqtt struct {
+	Broker    string `yaml:"broker"` // empty to disable the bridge
+	Prefix    string `yaml:"prefix"`
+	Discovery string `yaml:"discovery"`
+}
+
+func Default() Config {
+	return Config{
+		Backend:      "cmd",
+		Host:         "http://localhost:3000",
+		LogFile:      "volumguiLogs.txt",
+		PollInterval: time.Second,
+		Presets:      presets.DefaultPath(),
+		Bindings:     bindings.DefaultPath(),
+		Leds:         leds.DefaultPath(),
+		Control:      control.DefaultPath(),
+		Mpris:        true,
+		Mqtt:         Mqtt{Prefix: mqtt.DefaultPrefix, Discovery: mqtt.DefaultDiscovery},
+		Ui:           ui.DefaultOptions(),
+	}
+}
+
+// DefaultPath is config.yaml in the user config directory
+func DefaultPath() string {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "config.yaml"
+	}
+	return filepath.Join(dir, "volumgui", "config.yaml")
+}
+
+// Path is the config file read, empty without one
+func (c *Config) Path() string {
+	return c.path
+}
+
+// Source describes where a setting came from, like VOLUMGUI_HOST or
+// -host, empty for defaults
+func (c *Config) Source(key string) string {
+	// user themes only come from the file
+	if strings.HasPrefix(key, "ui.themes.") {
+		return c.path
+	}
+	return c.sources[key]
+}
+
+// Loader collects the flags of the settings and layers them over the
+// config file and the environment
+type Loader struct {
+	path  string            // -config
+	flags map[string]string // values of the flags given by setting key
+}
+
+// NewLoader registers -config and a flag for each setting on flags
+func NewLoader(flags *flag.FlagSet) *Loader {
+	l := &Loader{flags: make(map[string]string)}
+	defaults := Default()
+	flags.StringVar(&l.path, "config", "", fmt.Sprintf("yaml config file (default %s, or %sCONFIG)", DefaultPath(), envPrefix))
+	for _, s := range settings {
+		flags.Var(&flagValue{setting: s, def: s.format(&defaults), values: l.flags}, s.flag, s.usage)
+	}
+	return l
+}
+
+// Load layers the config file, the environment read with lookupEnv and
+// the flags over the defaults and validates the result
+func (l *Loader) Load(lookupEnv func(string) (string, bool)) (*Config, error) {
+	config := Default()
+	config.sources = make(map[string]string)
+
+	path, required := l.path, true
+	if path == "" {
+		path, _ = lookupEnv(envPrefix + "CONFIG")
+	}
+	if path == "" {
+		path, required = DefaultPath(), false
+	}
+	if err := config.read(path, required); err != nil {
+		return nil, err
+	}
+
+	for _, s := range settings {
+		name := s.env()
+		if value, ok := lookupEnv(name); ok {
+			if err := s.set(&config, value); err != nil {
+				return nil, fmt.Errorf("config: %s: %w", name, err)
+			}
+			config.sources[s.key] = name
+		}
+	}
+
+	for _, s := range settings {
+		if value, ok := l.flags[s.key]; ok {
+			// checked when the flags were parsed
+			s.set(&config, value)
+			config.sources[s.key] = "-" + s.flag
+		}
+	}
+
+	if err := config.validate(); err != nil {
+		return nil, err
+	}
+	return &config, nil
+}
+
+// read decodes the config file at path over the defaults, a missing file
+// is only an error when it was asked for
+func (c *Config) read(path string, required bool) error {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) && !required {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("config: %w", err)
+	}
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	decoder.KnownFields(true)
+	if err := decoder.Decode(c); err != nil && !errors.Is(err, io.EOF) {
+		return fmt.Errorf("config: %s: %w", path, err)
+	}
+
+	// the settings present in the file
+	var present map[string]interface{}
+	if err := yaml.Unmarshal(data, &present); err != nil {
+		return fmt.Errorf("config: %s: %w", path, err)
+	}
+	for _, s := range settings {
+		if s.in(present) {
+			c.sources[s.key] = path
+		}
+	}
+	c.path = path
+	return nil
+}
+
+// validate reports every invalid setting along with its source
+func (c *Config) validate() error {
+	var errs []error
+	check := func(key string, err error) {
+		if err == nil {
+			return
+		}
+		if source := c.Source(key); source != "" {
+			err = fmt.Errorf("config: %s (from %s): %w", key, source, err)
+		} else {
+			err = fmt.Errorf("config: %s: %w", key, err)
+		}
+		errs = append(errs, err)
+	}
+
+	switch c.Backend {
+	case "cmd", "socket", "rest":
+	default:
+		check("backend", fmt.Errorf("unknown backend %q, expected cmd, socket or rest", c.Backend))
+	}
+	check("host", checkUrl(c.Host, "http", "https"))
+	if c.LogFile == "" {
+		check("log_file", errors.New("must not be empty"))
+	}
+	if c.PollInterval <= 0 {
+		check("poll_interval", errors.New("must be positive"))
+	}
+	if c.Mqtt.Broker != "" {
+		check("mqtt.broker", checkUrl(c.Mqtt.Broker, "tcp", "ssl", "tls", "mqtt", "mqtts", "ws", "wss"))
+		if c.Mqtt.Prefix == "" {
+			check("mqtt.prefix", errors.New("must not be empty"))
+		}
+	}
+	if c.Ui.Marquee <= 0 {
+		check("ui.marquee", errors.New("must be positive"))
+	}
+	check("ui.wifi_interface", ui.CheckInterface(c.Ui.WifiInterface))
+	check("ui.theme", c.Ui.CheckTheme())
+	names := make([]string, 0, len(c.Ui.Themes))
+	for name := range c.Ui.Themes {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		check("ui.themes."+name, c.Ui.Themes[name].Validate())
+	}
+
+	return errors.Join(errs...)
+}
+
+func checkUrl(raw string, schemes ...string) error {
+	// parse errors would repeat the password
+	u, err := url.Parse(raw)
+	if err != nil {
+		return errors.New("invalid url")
+	}
+	for _, scheme := range schemes {
+		if u.Scheme == scheme && u.Host != "" {
+			return nil
+		}
+	}
+	return fmt.Errorf("%q is not a %s url with a host", redacted(raw), schemes[0])
+}
@@ -0,0 +1,170 @@
+package config
+
+import (
+	"bytes"
+	"flag"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+// load runs the loader on args and a fake environment
+func load(t *testing.T, args []string, env map[string]string) (*Config, error) {
+	t.Helper()
+	flags := flag.NewFlagSet("volumgui", flag.ContinueOnError)
+	flags.SetOutput(io.Discard)
+	loader := NewLoader(flags)
+	if err := flags.Parse(args); err != nil {
+		t.Fatal(err)
+	}
+	return loader.Load(func(name string) (string, bool) {
+		value, ok := env[name]
+		return value, ok
+	})
+}
+
+func writeConfig(t *testing.T, data string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	if err := os.WriteFile(path, []byte(data), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func Test_Layers(t *testing.T) {
+	path := writeConfig(t, `
+backend: socket
+host: http://volumio.local
+poll_interval: 3s
+mqtt:
+  broker: tcp://broker:1883
+ui:
+  theme: night
+  themes:
+    night:
+      base: solarized
+      show_border: true
+      title: {fg: cyan, modifier: bold underline}
+`)
+	env := map[string]string{
+		"VOLUMGUI_CONFIG":      path,
+		"VOLUMGUI_HOST":        "http://10.0.0.5:3000",
+		"VOLUMGUI_MQTT_PREFIX": "livingroom",
+		"VOLUMGUI_HTTP":        ":8080",
+		"VOLUMGUI_CONTROL":     "", // set but empty disables the socket
+	}
+	c, err := load(t, []string{"-backend", "rest", "-mpris=false", "-marquee", "250ms"}, env)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := Default()
+	want.Backend = "rest"
+	want.Host = "http://10.0.0.5:3000"
+	want.PollInterval = 3 * time.Second
+	want.Http = ":8080"
+	want.Control = ""
+	want.Mpris = false
+	want.Mqtt.Broker = "tcp://broker:1883"
+	want.Mqtt.Prefix = "livingroom"
+	want.Ui.Marquee = 250 * time.Millisecond
+	want.Ui.Theme = "night"
+	night := c.Ui.Themes["night"]
+	if night.Header.Fg != "33" || night.Title.Fg != "cyan" || !night.ShowBorder {
+		t.Errorf("night doesn't extend solarized: %+v", night)
+	}
+	want.Ui.Themes = c.Ui.Themes
+	want.path, want.sources = c.path, c.sources
+	if !reflect.DeepEqual(*c, want) {
+		t.Fatalf("expected %+v, got %+v", want, *c)
+	}
+
+	sources := map[string]string{
+		"backend":           "-backend",
+		"host":              "VOLUMGUI_HOST",
+		"poll_interval":     path,
+		"mqtt.broker":       path,
+		"mqtt.prefix":       "VOLUMGUI_MQTT_PREFIX",
+		"ui.theme":          path,
+		"ui.wifi_interface": "",
+		"mpris":             "-mpris",
+	}
+	for key, source := range sources {
+		if c.Source(key) != source {
+			t.Errorf("%s: expected source %q, got %q", key, source, c.Source(key))
+		}
+	}
+	if c.Path() != path {
+		t.Errorf("expected path %s, got %s", path, c.Path())
+	}
+
+	// the printed config reads back to the same values
+	var out bytes.Buffer
+	if err := c.Print(&out); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(out.String(), "host: http://10.0.0.5:3000 # VOLUMGUI_HOST") {
+		t.Errorf("source missing from\n%s", out.String())
+	}
+	printed, err := load(t, []string{"-config", writeConfig(t, out.String())}, nil)
+	if err != nil {
+		t.Fatalf("%v\n%s", err, out.String())
+	}
+	printed.path, printed.sources = c.path, c.sources
+	if !reflect.DeepEqual(*printed, *c) {
+		t.Fatalf("expected %+v, got %+v", *c, *printed)
+	}
+}
+
+func Test_Invalid(t *testing.T) {
+	tests := []struct {
+		args   []string
+		env    map[string]string
+		config string
+		want   []string
+	}{
+		{
+			args: []string{"-backend", "mpd", "-wifi", "wlan0; reboot"},
+			env:  map[string]string{"VOLUMGUI_UI_THEME": "neon"},
+			want: []string{
+				`backend (from -backend): unknown backend "mpd"`,
+				`ui.wifi_interface (from -wifi)`,
+				`ui.theme (from VOLUMGUI_UI_THEME): unknown theme "neon"`,
+			},
+		},
+		{
+			config: "host: volumio.local\nmqtt: {broker: 'tcp://user:secret@'}\n",
+			want:   []string{"host (from ", `mqtt.broker (from `, "user:xxxxx@"},
+		},
+		{config: "hots: http://volumio.local\n", want: []string{"field hots not found"}},
+		{config: "ui: {themes: {neon: {header: {fg: purple}}}}\n", want: []string{`ui.themes.neon (from `, `header: unknown color "purple"`}},
+		{config: "ui: {themes: {neon: {header: {colour: red}}}}\n", want: []string{"field colour not found"}},
+		{config: "ui: {themes: {neon: {base: neon}}}\n", want: []string{`unknown base theme "neon"`}},
+		{env: map[string]string{"VOLUMGUI_POLL_INTERVAL": "often"}, want: []string{`VOLUMGUI_POLL_INTERVAL: invalid duration "often"`}},
+		{args: []string{"-config", "/nonexistent/config.yaml"}, want: []string{"no such file"}},
+	}
+	for _, test := range tests {
+		args := test.args
+		if test.config != "" {
+			args = append(args, "-config", writeConfig(t, test.config))
+		}
+		_, err := load(t, args, test.env)
+		if err == nil {
+			t.Errorf("%v %v: expected an error", args, test.env)
+			continue
+		}
+		for _, want := range test.want {
+			if !strings.Contains(err.Error(), want) {
+				t.Errorf("expected %q in %q", want, err)
+			}
+		}
+		if strings.Contains(err.Error(), "secret") {
+			t.Errorf("password in %q", err)
+		}
+	}
+}
@@ -0,0 +1,64 @@
+package config
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Print writes the effective config as yaml usable as a config file,
+// settings not left at their default are commented with their source
+func (c *Config) Print(w io.Writer) error {
+	root := &yaml.Node{Kind: yaml.MappingNode}
+	if c.path != "" {
+		root.HeadComment = "config file: " + c.path
+	} else {
+		root.HeadComment = "no config file, expected at " + DefaultPath()
+	}
+
+	for _, s := range settings {
+		keys := strings.Split(s.key, ".")
+		parent := root
+		for _, key := range keys[:len(keys)-1] {
+			parent = mapping(parent, key)
+		}
+		value := &yaml.Node{Kind: yaml.ScalarNode, Value: redacted(s.format(c))}
+		// strings like "on" stay strings
+		if _, ok := s.field(c).(*string); ok {
+			value.Tag = "!!str"
+		}
+		value.LineComment = c.Source(s.key)
+		parent.Content = append(parent.Content, &yaml.Node{Kind: yaml.ScalarNode, Value: keys[len(keys)-1]}, value)
+	}
+
+	// user themes are only read from the file
+	if len(c.Ui.Themes) > 0 {
+		themes := &yaml.Node{}
+		if err := themes.Encode(c.Ui.Themes); err != nil {
+			return fmt.Errorf("config: %w", err)
+		}
+		ui := mapping(root, "ui")
+		ui.Content = append(ui.Content, &yaml.Node{Kind: yaml.ScalarNode, Value: "themes"}, themes)
+	}
+
+	encoder := yaml.NewEncoder(w)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(root); err != nil {
+		return fmt.Errorf("config: %w", err)
+	}
+	return encoder.Close()
+}
+
+// mapping returns the mapping under key of parent, adding it on first use
+func mapping(parent *yaml.Node, key string) *yaml.Node {
+	for i := 0; i+1 < len(parent.Content); i += 2 {
+		if parent.Content[i].Value == key {
+			return parent.Content[i+1]
+		}
+	}
+	child := &yaml.Node{Kind: yaml.MappingNode}
+	parent.Content = append(parent.Content, &yaml.Node{Kind: yaml.ScalarNode, Value: key}, child)
+	return child
+}
@@ -0,0 +1,252 @@
+package control
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"time"
+
+	"volumgui/actions"
+	"volumgui/client"
+)
+
+const (
+	actionTimeout  = 5 * time.Second
+	maxRequestSize = 64 * 1024
+)
+
+// commands handled by the server, every other command is run as an
+// action like "volume +5"
+const (
+	StateCommand       = "state"
+	SubscribeCommand   = "subscribe" // send events on state and connection changes
+	UnsubscribeCommand = "unsubscribe"
+)
+
+// events sent to subscribers
+const (
+	StateEvent = "state"
+	ConnEvent  = "conn"
+)
+
+// Request is a line like {"id": 1, "command": "seek 1:30"}
+type Request struct {
+	Id      int    `json:"id,omitempty"` // echoed in the reply
+	Command string `json:"command"`
+}
+
+// Reply answers a request, events have no id
+type Reply struct {
+	Id    int           `json:"id,omitempty"`
+	Ok    bool          `json:"ok"`
+	Error string        `json:"error,omitempty"`
+	Event string        `json:"event,omitempty"`
+	State *client.State `json:"state,omitempty"`
+	Conn  string        `json:"conn,omitempty"`
+}
+
+// DefaultPath is volumgui.sock in $XDG_RUNTIME_DIR, or in the temporary
+// directory with the uid in the name if it isn't set
+func DefaultPath() string {
+	if dir := os.Getenv("XDG_RUNTIME_DIR"); dir != "" {
+		return filepath.Join(dir, "volumgui.sock")
+	}
+	return filepath.Join(os.TempDir(), "volumgui-"+strconv.Itoa(os.Getuid())+".sock")
+}
+
+// Server runs the commands of local clients on a unix socket
+type Server struct {
+	Path     string
+	Hub      *client.StateHub
+	Runner   *actions.Runner
+	DoneChan <-chan bool
+	InfoLog  *log.Logger
+	ErrorLog *log.Logger
+}
+
+func NewServer(path string, hub *client.StateHub, runner *actions.Runner, done_chan <-chan bool, info_log *log.Logger, error_log *log.Logger) *Server {
+	return &Server{
+		Path:     path,
+		Hub:      hub,
+		Runner:   runner,
+		DoneChan: done_chan,
+		InfoLog:  info_log,
+		ErrorLog: error_log,
+	}
+}
+
+// Listen creates the socket, a socket left over by a crashed instance is
+// replaced but a running instance is not
+func (s *Server) Listen() (net.Listener, error) {
+	if conn, err := net.Dial("unix", s.Path); err == nil {
+		conn.Close()
+		return nil, fmt.Errorf("control: %s is in use by another volumgui", s.Path)
+	}
+	if err := os.Remove(s.Path); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return nil, fmt.Errorf("control: %w", err)
+	}
+	l, err := net.Listen("unix", s.Path)
+	if err != nil {
+		return nil, fmt.Errorf("control: %w", err)
+	}
+	// only the user may drive the player
+	if err := os.Chmod(s.Path, 0600); err != nil {
+		l.Close()
+		return nil, fmt.Errorf("control: %w", err)
+	}
+	return l, nil
+}
+
+// Serve accepts connections on l until DoneChan is closed, closing l
+// removes the socket
+func (s *Server) Serve(l net.Listener) {
+	go func() {
+		<-s.DoneChan
+		l.Close()
+	}()
+	s.InfoLog.Printf("control socket listening on %s", l.Addr())
+	for {
+		conn, err := l.Accept()
+		if err != nil {
+			select {
+			case <-s.DoneChan:
+			default:
+				s.ErrorLog.Printf("control: %v", err)
+			}
+			return
+		}
+		go s.handle(conn)
+	}
+}
+
+// session of a connected client, replies and events share the connection
+type session struct {
+	s       *Server
+	conn    net.Conn
+	mu      sync.Mutex
+	encoder *json.Encoder
+	stop    chan bool // closed to end the subscription, nil without one
+	states  <-chan client.State
+	conns   <-chan client.ConnState
+}
+
+func (s *Server) handle(conn net.Conn) {
+	ss := session{s: s, conn: conn, encoder: json.NewEncoder(conn)}
+	defer ss.close()
+	finished := make(chan bool)
+	defer close(finished)
+	go func() {
+		select {
+		case <-s.DoneChan:
+			conn.Close()
+		case <-finished:
+		}
+	}()
+
+	scanner := bufio.NewScanner(conn)
+	scanner.Buffer(make([]byte, 4096), maxRequestSize)
+	for scanner.Scan() {
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+		var request Request
+		if err := json.Unmarshal(scanner.Bytes(), &request); err != nil {
+			ss.send(Reply{Error: fmt.Sprintf("invalid request: %v", err)})
+			continue
+		}
+		ss.run(request)
+	}
+}
+
+// run replies to request, subscribers get the reply before the first event
+func (ss *session) run(request Request) {
+	reply := Reply{Id: request.Id, Ok: true}
+	switch request.Command {
+	case StateCommand:
+		state := ss.s.Hub.Current()
+		reply.State, reply.Conn = &state, ss.s.Hub.Conn().String()
+	case SubscribeCommand:
+		ss.send(reply)
+		ss.subscribe()
+		return
+	case UnsubscribeCommand:
+		ss.unsubscribe()
+	default:
+		if err := ss.runAction(request.Command); err != nil {
+			reply.Ok, reply.Error = false, err.Error()
+		}
+	}
+	ss.send(reply)
+}
+
+func (ss *session) runAction(command string) error {
+	action, err := actions.Parse(command)
+	if err != nil {
+		return err
+	}
+	// the display has to run these itself
+	if action.UI() {
+		return fmt.Errorf("control: %s is not available on the control socket", action)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), actionTimeout)
+	defer cancel()
+	if err := ss.s.Runner.Run(ctx, action); err != nil {
+		ss.s.ErrorLog.Println(err)
+		return err
+	}
+	return nil
+}
+
+// subscribe forwards the states and connection states of the hub, the
+// latest ones are sent right away
+func (ss *session) subscribe() {
+	if ss.stop != nil {
+		return
+	}
+	stop := make(chan bool)
+	states, conns := ss.s.Hub.Subscribe(), ss.s.Hub.SubscribeConn()
+	ss.stop, ss.states, ss.conns = stop, states, conns
+	go func() {
+		for {
+			select {
+			case state := <-states:
+				ss.send(Reply{Ok: true, Event: StateEvent, State: &state})
+			case conn := <-conns:
+				ss.send(Reply{Ok: true, Event: ConnEvent, Conn: conn.String()})
+			case <-stop:
+				return
+			}
+		}
+	}()
+}
+
+func (ss *session) unsubscribe() {
+	if ss.stop != nil {
+		ss.s.Hub.Unsubscribe(ss.states)
+		ss.s.Hub.UnsubscribeConn(ss.conns)
+		close(ss.stop)
+		ss.stop = nil
+	}
+}
+
+func (ss *session) send(reply Reply) {
+	ss.mu.Lock()
+	defer ss.mu.Unlock()
+	// a failed write ends the session on the next read
+	if err := ss.encoder.Encode(reply); err != nil {
+		ss.conn.Close()
+	}
+}
+
+func (ss *session) close() {
+	ss.unsubscribe()
+	ss.conn.Close()
+}
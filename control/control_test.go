@@ -0,0 +1,156 @@
+package control
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"io"
+	"log"
+	"net"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"volumgui/actions"
+	"volumgui/client"
+)
+
+// client recording its calls
+type callClient struct {
+	client.ClientInterface
+	calls chan string
+}
+
+func (c *callClient) Next(ctx context.Context) error { c.calls <- "next"; return nil }
+func (c *callClient) Seek(ctx context.Context, position time.Duration) error {
+	c.calls <- "seek " + position.String()
+	return nil
+}
+
+type testConn struct {
+	t       *testing.T
+	conn    net.Conn
+	replies *bufio.Scanner
+}
+
+func (c *testConn) send(request string) {
+	c.t.Helper()
+	if _, err := c.conn.Write([]byte(request + "\n")); err != nil {
+		c.t.Fatal(err)
+	}
+}
+
+func (c *testConn) receive() Reply {
+	c.t.Helper()
+	c.conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	if !c.replies.Scan() {
+		c.t.Fatalf("no reply: %v", c.replies.Err())
+	}
+	var reply Reply
+	if err := json.Unmarshal(c.replies.Bytes(), &reply); err != nil {
+		c.t.Fatal(err)
+	}
+	return reply
+}
+
+func newTestServer(t *testing.T) (*Server, *callClient, chan client.State) {
+	t.Helper()
+	state_chan := make(chan client.State)
+	done_chan := make(chan bool)
+	hub := client.NewStateHub(state_chan, nil, done_chan)
+	go hub.Run()
+
+	c := callClient{calls: make(chan string, 4)}
+	logger := log.New(io.Discard, "", 0)
+	runner := actions.NewRunner(&c, hub.Current, nil, logger, logger)
+	s := NewServer(filepath.Join(t.TempDir(), "volumgui.sock"), hub, runner, done_chan, logger, logger)
+	l, err := s.Listen()
+	if err != nil {
+		t.Fatal(err)
+	}
+	go s.Serve(l)
+	t.Cleanup(func() { close(done_chan) })
+	return s, &c, state_chan
+}
+
+func dial(t *testing.T, s *Server) *testConn {
+	t.Helper()
+	conn, err := net.Dial("unix", s.Path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return &testConn{t: t, conn: conn, replies: bufio.NewScanner(conn)}
+}
+
+func Test_ServerCommands(t *testing.T) {
+	s, c, state_chan := newTestServer(t)
+	conn := dial(t, s)
+
+	conn.send(`{"id": 1, "command": "next"}`)
+	if reply := conn.receive(); reply.Id != 1 || !reply.Ok {
+		t.Fatalf("unexpected reply %+v", reply)
+	}
+	conn.send(`{"id": 2, "command": "seek 1:30"}`)
+	conn.receive()
+	for _, want := range []string{"next", "seek 1m30s"} {
+		if call := <-c.calls; call != want {
+			t.Fatalf("expected %s, got %s", want, call)
+		}
+	}
+
+	for _, request := range []string{`{"id": 3, "command": "dance"}`, `{"id": 3, "command": "quit"}`, `{"id": 3}`} {
+		conn.send(request)
+		if reply := conn.receive(); reply.Ok || reply.Error == "" {
+			t.Errorf("%s: unexpected reply %+v", request, reply)
+		}
+	}
+	conn.send(`not json`)
+	if reply := conn.receive(); reply.Ok || reply.Id != 0 {
+		t.Errorf("unexpected reply %+v", reply)
+	}
+
+	state_chan <- client.State{Title: "Crossroads", Volume: 30}
+	conn.send(`{"id": 4, "command": "state"}`)
+	if reply := conn.receive(); reply.State == nil || reply.State.Title != "Crossroads" || reply.Conn != "connected" {
+		t.Fatalf("unexpected state %+v", reply)
+	}
+
+	// a second instance must not take over the socket
+	if _, err := s.Listen(); err == nil {
+		t.Fatal("socket in use was replaced")
+	}
+}
+
+func Test_ServerSubscribe(t *testing.T) {
+	s, _, state_chan := newTestServer(t)
+	conn := dial(t, s)
+
+	// the latest state follows the reply
+	state_chan <- client.State{Title: "Sunshine of Your Love"}
+	for s.Hub.State().Title == "" {
+		time.Sleep(time.Millisecond)
+	}
+	conn.send(`{"id": 1, "command": "subscribe"}`)
+	if reply := conn.receive(); reply.Id != 1 || !reply.Ok {
+		t.Fatalf("unexpected reply %+v", reply)
+	}
+	if event := conn.receive(); event.State == nil || event.State.Title != "Sunshine of Your Love" {
+		t.Fatalf("unexpected event %+v", event)
+	}
+	state_chan <- client.State{Title: "Badge"}
+	event := conn.receive()
+	if event.Event != StateEvent || event.State.Title != "Badge" {
+		t.Fatalf("unexpected event %+v", event)
+	}
+
+	conn.send(`{"id": 2, "command": "unsubscribe"}`)
+	if reply := conn.receive(); reply.Id != 2 {
+		t.Fatalf("unexpected reply %+v", reply)
+	}
+	state_chan <- client.State{Title: "Politician"}
+	conn.send(`{"id": 3, "command": "state"}`)
+	if reply := conn.receive(); reply.Id != 3 || reply.State.Title != "Politician" {
+		t.Fatalf("event after unsubscribe: %+v", reply)
+	}
+}
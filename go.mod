@@ -3,9 +3,13 @@ module volumgui
 go 1.21.13
 
 require (
+	github.com/eclipse/paho.mqtt.golang v1.4.3
 	github.com/gizak/termui/v3 v3.1.0
+	github.com/godbus/dbus/v5 v5.1.0
 	github.com/googollee/go-socket.io v1.8.0-rc.1
+	github.com/mochi-mqtt/server/v2 v2.7.9
 	github.com/stianeikeland/go-rpio/v4 v4.6.0
+	gopkg.in/yaml.v3 v3.0.1
 )
 
 require (
@@ -15,6 +19,8 @@ require (
 	github.com/mattn/go-runewidth v0.0.2 // indirect
 	github.com/mitchellh/go-wordwrap v0.0.0-20150314170334-ad45545899c7 // indirect
 	github.com/nsf/termbox-go v0.0.0-20190121233118-02980233997d // indirect
+	github.com/rs/xid v1.4.0 // indirect
 	golang.org/x/exp v0.0.0-20230713183714-613f0c0eb8a1 // indirect
-	gopkg.in/yaml.v3 v3.0.1 // indirect
+	golang.org/x/net v0.33.0 // indirect
+	golang.org/x/sync v0.1.0 // indirect
 )
@@ -0,0 +1,171 @@
+package leds
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	"volumgui/client"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Config wires LEDs to the outputs of chained 74HC595 shift registers
+type Config struct {
+	Spi        int `yaml:"spi"`         // 0 for SPI0
+	ChipSelect int `yaml:"chip_select"` // 0 for CE0
+	LatchPin   int `yaml:"latch_pin"`   // bcm number of the RCLK pin
+	Chips      int `yaml:"chips"`
+	Layout     `yaml:",inline"`
+}
+
+// Layout holds the output of each LED, output 0 is QA of the chip next
+// to the pi and output 8 is QA of the second chip, -1 for LEDs that
+// aren't fitted
+type Layout struct {
+	Status  int   `yaml:"status"` // lit while connected, blinks while connecting or on errors
+	Play    int   `yaml:"play"`
+	Pause   int   `yaml:"pause"`
+	Mute    int   `yaml:"mute"`
+	Random  int   `yaml:"random"`
+	Repeat  int   `yaml:"repeat"`
+	Volume  []int `yaml:"volume"`  // bar graph, lowest LED first
+	Presets []int `yaml:"presets"` // slot 1 first, lit while the slot plays
+}
+
+func DefaultLayout() Layout {
+	return Layout{Status: -1, Play: -1, Pause: -1, Mute: -1, Random: -1, Repeat: -1}
+}
+
+// DefaultPath is leds.yaml in the user config directory
+func DefaultPath() string {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "leds.yaml"
+	}
+	return filepath.Join(dir, "volumgui", "leds.yaml")
+}
+
+// Load reads the LED config at path, a missing file means there are no
+// LEDs and gives nil
+func Load(path string) (*Config, error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("leds: %w", err)
+	}
+	return Parse(data)
+}
+
+func Parse(data []byte) (*Config, error) {
+	config := Config{Layout: DefaultLayout()}
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	decoder.KnownFields(true)
+	if err := decoder.Decode(&config); err != nil && !errors.Is(err, io.EOF) {
+		return nil, fmt.Errorf("leds: %w", err)
+	}
+	if err := config.validate(); err != nil {
+		return nil, fmt.Errorf("leds: %w", err)
+	}
+	return &config, nil
+}
+
+func (c Config) validate() error {
+	if c.Chips < 1 {
+		return errors.New("chips must be at least 1")
+	}
+	outputs := c.Chips * 8
+	used := make(map[int]bool)
+	for _, output := range c.outputs() {
+		if output < 0 {
+			continue
+		}
+		if output >= outputs {
+			return fmt.Errorf("output %d out of range [0, %d]", output, outputs-1)
+		}
+		if used[output] {
+			return fmt.Errorf("output %d used twice", output)
+		}
+		used[output] = true
+	}
+	return nil
+}
+
+// outputs returns the outputs of all LEDs
+func (l Layout) outputs() []int {
+	outputs := []int{l.Status, l.Play, l.Pause, l.Mute, l.Random, l.Repeat}
+	outputs = append(outputs, l.Volume...)
+	return append(outputs, l.Presets...)
+}
+
+// Mode selects how the LEDs signal the connection
+type Mode int
+
+const (
+	Normal     Mode = iota
+	Connecting      // slow blink
+	Failed          // double flash
+)
+
+// ModeOf returns the mode showing a connection state
+func ModeOf(conn client.ConnState) Mode {
+	switch conn {
+	case client.Connecting:
+		return Connecting
+	case client.Disconnected:
+		return Failed
+	default:
+		return Normal
+	}
+}
+
+// patterns are played one step per blinkInterval
+var patterns = map[Mode][]bool{
+	Connecting: {true, true, true, true, false, false, false, false},
+	Failed:     {true, false, true, false, false, false, false, false},
+}
+
+// Frame returns the bytes to write to chips shift registers for the
+// state, step counts the blink intervals. Without a status LED the
+// whole panel blinks while connecting or on errors
+func (l Layout) Frame(chips int, state client.State, mode Mode, step int, preset int) []byte {
+	frame := make([]byte, chips)
+	set := func(output int, on bool) {
+		if on && output >= 0 && output < chips*8 {
+			frame[output/8] |= 1 << (output % 8)
+		}
+	}
+
+	blink := true
+	if pattern, ok := patterns[mode]; ok {
+		blink = pattern[step%len(pattern)]
+		if l.Status < 0 {
+			for _, output := range l.outputs() {
+				set(output, blink)
+			}
+			return frame
+		}
+	}
+	set(l.Status, blink)
+
+	set(l.Play, state.Status == "play")
+	set(l.Pause, state.Status == "pause")
+	set(l.Mute, state.Mute)
+	set(l.Random, state.Random)
+	set(l.Repeat, state.Repeat || state.RepeatSingle)
+
+	// round up so any volume above zero lights the first LED
+	lit := (state.Volume*len(l.Volume) + 99) / 100
+	for i, output := range l.Volume {
+		set(output, i < lit)
+	}
+	if preset > 0 && preset <= len(l.Presets) {
+		set(l.Presets[preset-1], true)
+	}
+	return frame
+}
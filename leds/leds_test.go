@@ -0,0 +1,165 @@
+package leds
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"log"
+	"testing"
+	"time"
+
+	"volumgui/client"
+	"volumgui/multiplexer"
+)
+
+const testConfig = `
+chips: 2
+status: 0
+play: 1
+pause: 2
+mute: 3
+random: 4
+repeat: 5
+volume: [8, 9, 10, 11]
+presets: [12, 13, 14]
+`
+
+func Test_Parse(t *testing.T) {
+	config, err := Parse([]byte(testConfig))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if config.Chips != 2 || config.Repeat != 5 || len(config.Volume) != 4 {
+		t.Fatalf("unexpected config %+v", config)
+	}
+	if config, err := Parse([]byte("chips: 1\nmute: 7\n")); err != nil || config.Status != -1 {
+		t.Fatalf("unset LEDs should be -1: %+v %v", config, err)
+	}
+
+	for _, config := range []string{
+		"chips: 0\n",
+		"chips: 1\nplay: 8\n",
+		"chips: 1\nplay: 1\npause: 1\n",
+		"chips: 1\nblink: 1\n",
+	} {
+		if _, err := Parse([]byte(config)); err == nil {
+			t.Errorf("expected an error for %q", config)
+		}
+	}
+}
+
+func Test_Frame(t *testing.T) {
+	config, err := Parse([]byte(testConfig))
+	if err != nil {
+		t.Fatal(err)
+	}
+	layout := config.Layout
+
+	tests := []struct {
+		name   string
+		state  client.State
+		mode   Mode
+		step   int
+		preset int
+		want   []byte
+	}{
+		{"idle", client.State{}, Normal, 0, 0, []byte{0x01, 0x00}},
+		{"playing", client.State{Status: "play", Volume: 60, Random: true}, Normal, 0, 2, []byte{0x13, 0x27}},
+		{"paused", client.State{Status: "pause", Volume: 100, Mute: true, RepeatSingle: true}, Normal, 0, 0, []byte{0x2d, 0x0f}},
+		{"connecting on", client.State{Status: "play"}, Connecting, 3, 0, []byte{0x03, 0x00}},
+		{"connecting off", client.State{Status: "play"}, Connecting, 4, 0, []byte{0x02, 0x00}},
+		{"failed off", client.State{}, Failed, 1, 0, []byte{0x00, 0x00}},
+		{"failed on", client.State{}, Failed, 10, 0, []byte{0x01, 0x00}},
+	}
+	for _, test := range tests {
+		got := layout.Frame(config.Chips, test.state, test.mode, test.step, test.preset)
+		if !bytes.Equal(got, test.want) {
+			t.Errorf("%s: expected %#v, got %#v", test.name, test.want, got)
+		}
+	}
+
+	// without a status LED the whole panel blinks
+	layout.Status = -1
+	if got := layout.Frame(config.Chips, client.State{}, Connecting, 0, 0); !bytes.Equal(got, []byte{0x3e, 0x7f}) {
+		t.Errorf("unexpected panel blink %#v", got)
+	}
+}
+
+func Test_Mapper(t *testing.T) {
+	config, err := Parse([]byte(testConfig))
+	if err != nil {
+		t.Fatal(err)
+	}
+	bus := multiplexer.NewFakeSPI()
+	sipo, err := multiplexer.NewSipo(&multiplexer.FakePin{}, config.Chips, bus)
+	if err != nil {
+		t.Fatal(err)
+	}
+	preset := func(uri string) int {
+		if uri == "webradio/fip" {
+			return 1
+		}
+		return 0
+	}
+	m := NewMapper(config.Layout, sipo, preset, log.New(io.Discard, "", 0))
+
+	state_chan := make(chan client.State)
+	done_chan := make(chan bool)
+	stopped := make(chan bool)
+	go func() {
+		m.Run(state_chan, nil, nil, done_chan)
+		close(stopped)
+	}()
+	state_chan <- client.State{Status: "play", Uri: "webradio/fip"}
+	state_chan <- client.State{Status: "play", Uri: "webradio/fip"}
+	time.Sleep(3 * blinkInterval)
+	close(done_chan)
+	<-stopped
+
+	// unchanged frames aren't written again, the chain is sent reversed
+	want := [][]byte{{0x00, 0x01}, {0x10, 0x03}, {0x00, 0x00}}
+	written := bus.Written()
+	if len(written) != len(want) {
+		t.Fatalf("expected %d writes, got %#v", len(want), written)
+	}
+	for i := range want {
+		if !bytes.Equal(written[i], want[i]) {
+			t.Errorf("write %d: expected %#v, got %#v", i, want[i], written[i])
+		}
+	}
+	if _, err := bus.Receive(1); err != multiplexer.ErrClosed {
+		t.Fatalf("bus not closed: %v", err)
+	}
+}
+
+// clients without a connection signal failures through the errors
+func Test_MapperErrors(t *testing.T) {
+	config, err := Parse([]byte(testConfig))
+	if err != nil {
+		t.Fatal(err)
+	}
+	bus := multiplexer.NewFakeSPI()
+	sipo, err := multiplexer.NewSipo(&multiplexer.FakePin{}, config.Chips, bus)
+	if err != nil {
+		t.Fatal(err)
+	}
+	m := NewMapper(config.Layout, sipo, nil, log.New(io.Discard, "", 0))
+
+	error_chan := make(chan error)
+	done_chan := make(chan bool)
+	stopped := make(chan bool)
+	go func() {
+		m.Run(nil, nil, error_chan, done_chan)
+		close(stopped)
+	}()
+	error_chan <- errors.New("volumio: play: timeout")
+	time.Sleep(3 * blinkInterval)
+	close(done_chan)
+	<-stopped
+
+	// the status LED is on, then flashes off
+	written := bus.Written()
+	if len(written) < 3 || !bytes.Equal(written[0], []byte{0x00, 0x01}) || !bytes.Equal(written[1], []byte{0x00, 0x00}) {
+		t.Fatalf("expected the failed pattern, got %#v", written)
+	}
+}
@@ -0,0 +1,105 @@
+package leds
+
+import (
+	"bytes"
+	"log"
+	"time"
+
+	"volumgui/client"
+	"volumgui/multiplexer"
+
+	"github.com/stianeikeland/go-rpio/v4"
+)
+
+const (
+	blinkInterval = 125 * time.Millisecond
+	failedFor     = 2 * time.Second // errors show the failed pattern this long
+)
+
+// Mapper mirrors the client state on the LEDs of a sipo
+type Mapper struct {
+	Layout   Layout
+	Sipo     *multiplexer.SIPO
+	Preset   func(uri string) int // slot holding uri, nil without presets
+	ErrorLog *log.Logger
+	last     []byte
+}
+
+func NewMapper(layout Layout, sipo *multiplexer.SIPO, preset func(uri string) int, error_log *log.Logger) *Mapper {
+	return &Mapper{
+		Layout:   layout,
+		Sipo:     sipo,
+		Preset:   preset,
+		ErrorLog: error_log,
+	}
+}
+
+// Open opens the shift registers of config on the spi bus of the pi
+func Open(config Config, preset func(uri string) int, error_log *log.Logger) (*Mapper, error) {
+	bus, err := multiplexer.OpenRpioSPI(rpio.SpiDev(config.Spi), multiplexer.ChipSelect(config.ChipSelect))
+	if err != nil {
+		return nil, err
+	}
+	sipo, err := multiplexer.NewSipo(multiplexer.NewRpioPin(config.LatchPin), config.Chips, bus)
+	if err != nil {
+		bus.Close()
+		return nil, err
+	}
+	return NewMapper(config.Layout, sipo, preset, error_log), nil
+}
+
+// Run updates the LEDs on every state and connection change until
+// done_chan is closed, then turns them off. conn_chan may be nil for
+// clients without a connection, error_chan reports failed commands and
+// polls, clients without a connection only signal failures this way
+func (m *Mapper) Run(state_chan <-chan client.State, conn_chan <-chan client.ConnState, error_chan <-chan error, done_chan <-chan bool) {
+	ticker := time.NewTicker(blinkInterval)
+	defer ticker.Stop()
+
+	var state client.State
+	var failed_until time.Time
+	conn_mode, step := Normal, 0
+	for {
+		mode := conn_mode
+		if time.Now().Before(failed_until) {
+			mode = Failed
+		}
+		preset := 0
+		if m.Preset != nil {
+			preset = m.Preset(state.Uri)
+		}
+		m.write(m.Layout.Frame(m.Sipo.NDev, state, mode, step, preset))
+
+		select {
+		case state = <-state_chan:
+		case conn := <-conn_chan:
+			conn_mode, step = ModeOf(conn), 0
+		case <-error_chan:
+			// repeated errors keep the pattern going
+			if mode != Failed {
+				step = 0
+			}
+			failed_until = time.Now().Add(failedFor)
+		case <-ticker.C:
+			step++
+		case <-done_chan:
+			m.write(make([]byte, m.Sipo.NDev))
+			if err := m.Sipo.Close(); err != nil {
+				m.ErrorLog.Println(err)
+			}
+			return
+		}
+	}
+}
+
+// write skips frames that are already shown
+func (m *Mapper) write(frame []byte) {
+	if bytes.Equal(frame, m.last) {
+		return
+	}
+	if err := m.Sipo.Write(frame); err != nil {
+		m.ErrorLog.Println(err)
+		return
+	}
+	m.last = frame
+}
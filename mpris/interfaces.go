@@ -0,0 +1,193 @@
+package mpris
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"time"
+
+	"github.com/godbus/dbus/v5"
+)
+
+// org.mpris.MediaPlayer2, the player can't be raised or quit remotely
+type root struct{ m *Mpris }
+
+func (r root) Raise() *dbus.Error { return nil }
+func (r root) Quit() *dbus.Error  { return nil }
+
+// org.mpris.MediaPlayer2.Player
+type player struct{ m *Mpris }
+
+func (p player) Next() *dbus.Error      { return p.m.run("next") }
+func (p player) Previous() *dbus.Error  { return p.m.run("prev") }
+func (p player) Pause() *dbus.Error     { return p.m.run("pause") }
+func (p player) PlayPause() *dbus.Error { return p.m.run("toggle") }
+func (p player) Stop() *dbus.Error      { return p.m.run("stop") }
+func (p player) Play() *dbus.Error      { return p.m.run("play") }
+
+// SeekBy is exported as Seek, it moves by offset microseconds
+func (p player) SeekBy(offset int64) *dbus.Error {
+	d := time.Duration(offset) * time.Microsecond
+	arg := d.String()
+	if d >= 0 {
+		arg = "+" + arg
+	}
+	return p.m.run("seek " + arg)
+}
+
+// SetPosition seeks to position microseconds, calls for another track
+// are ignored as the spec requires
+func (p player) SetPosition(track dbus.ObjectPath, position int64) *dbus.Error {
+	state := p.m.Hub.Current()
+	if track != trackId(state.Uri) || position < 0 || position > state.Length().Microseconds() {
+		return nil
+	}
+	return p.m.run("seek " + strconv.FormatInt(position/1000000, 10))
+}
+
+func (p player) OpenUri(uri string) *dbus.Error {
+	return dbus.NewError("org.freedesktop.DBus.Error.NotSupported", []interface{}{"volumgui can't open uris"})
+}
+
+// org.freedesktop.DBus.Properties
+type properties struct{ m *Mpris }
+
+func (p properties) all(iface string) (map[string]dbus.Variant, *dbus.Error) {
+	switch iface {
+	case rootInterface:
+		return p.m.rootProperties(), nil
+	case playerInterface:
+		return p.m.playerProperties(p.m.Hub.Current()), nil
+	}
+	return nil, dbus.NewError("org.freedesktop.DBus.Error.UnknownInterface", []interface{}{iface})
+}
+
+func (p properties) Get(iface string, name string) (dbus.Variant, *dbus.Error) {
+	props, err := p.all(iface)
+	if err != nil {
+		return dbus.Variant{}, err
+	}
+	value, ok := props[name]
+	if !ok {
+		return dbus.Variant{}, dbus.NewError("org.freedesktop.DBus.Error.UnknownProperty", []interface{}{name})
+	}
+	return value, nil
+}
+
+func (p properties) GetAll(iface string) (map[string]dbus.Variant, *dbus.Error) {
+	return p.all(iface)
+}
+
+// Set changes the writable player properties, the change is announced
+// once the new state arrives
+func (p properties) Set(iface string, name string, value dbus.Variant) *dbus.Error {
+	if iface != playerInterface {
+		return dbus.NewError("org.freedesktop.DBus.Error.PropertyReadOnly", []interface{}{name})
+	}
+	invalid := dbus.NewError("org.freedesktop.DBus.Error.InvalidArgs", []interface{}{fmt.Sprintf("invalid %s %v", name, value)})
+	switch name {
+	case "Volume":
+		volume, ok := value.Value().(float64)
+		if !ok {
+			return invalid
+		}
+		volume = math.Max(0, math.Min(1, volume))
+		return p.m.run("volume " + strconv.Itoa(int(math.Round(volume*100))))
+	case "Shuffle":
+		shuffle, ok := value.Value().(bool)
+		if !ok {
+			return invalid
+		}
+		if shuffle {
+			return p.m.run("random on")
+		}
+		return p.m.run("random off")
+	case "LoopStatus":
+		modes := map[string]string{"None": "off", "Track": "single", "Playlist": "all"}
+		status, _ := value.Value().(string)
+		mode, ok := modes[status]
+		if !ok {
+			return invalid
+		}
+		return p.m.run("repeat " + mode)
+	case "Rate":
+		// only normal speed is supported
+		return nil
+	}
+	return dbus.NewError("org.freedesktop.DBus.Error.PropertyReadOnly", []interface{}{name})
+}
+
+const introspection = `<node>
+  <interface name="org.mpris.MediaPlayer2">
+    <method name="Raise"/>
+    <method name="Quit"/>
+    <property name="CanQuit" type="b" access="read"/>
+    <property name="CanRaise" type="b" access="read"/>
+    <property name="HasTrackList" type="b" access="read"/>
+    <property name="Identity" type="s" access="read"/>
+    <property name="SupportedUriSchemes" type="as" access="read"/>
+    <property name="SupportedMimeTypes" type="as" access="read"/>
+  </interface>
+  <interface name="org.mpris.MediaPlayer2.Player">
+    <method name="Next"/>
+    <method name="Previous"/>
+    <method name="Pause"/>
+    <method name="PlayPause"/>
+    <method name="Stop"/>
+    <method name="Play"/>
+    <method name="Seek">
+      <arg name="Offset" type="x" direction="in"/>
+    </method>
+    <method name="SetPosition">
+      <arg name="TrackId" type="o" direction="in"/>
+      <arg name="Position" type="x" direction="in"/>
+    </method>
+    <method name="OpenUri">
+      <arg name="Uri" type="s" direction="in"/>
+    </method>
+    <signal name="Seeked">
+      <arg name="Position" type="x"/>
+    </signal>
+    <property name="PlaybackStatus" type="s" access="read"/>
+    <property name="LoopStatus" type="s" access="readwrite"/>
+    <property name="Rate" type="d" access="readwrite"/>
+    <property name="Shuffle" type="b" access="readwrite"/>
+    <property name="Metadata" type="a{sv}" access="read"/>
+    <property name="Volume" type="d" access="readwrite"/>
+    <property name="Position" type="x" access="read"/>
+    <property name="MinimumRate" type="d" access="read"/>
+    <property name="MaximumRate" type="d" access="read"/>
+    <property name="CanGoNext" type="b" access="read"/>
+    <property name="CanGoPrevious" type="b" access="read"/>
+    <property name="CanPlay" type="b" access="read"/>
+    <property name="CanPause" type="b" access="read"/>
+    <property name="CanSeek" type="b" access="read"/>
+    <property name="CanControl" type="b" access="read"/>
+  </interface>
+  <interface name="org.freedesktop.DBus.Properties">
+    <method name="Get">
+      <arg name="interface_name" type="s" direction="in"/>
+      <arg name="property_name" type="s" direction="in"/>
+      <arg name="value" type="v" direction="out"/>
+    </method>
+    <method name="GetAll">
+      <arg name="interface_name" type="s" direction="in"/>
+      <arg name="properties" type="a{sv}" direction="out"/>
+    </method>
+    <method name="Set">
+      <arg name="interface_name" type="s" direction="in"/>
+      <arg name="property_name" type="s" direction="in"/>
+      <arg name="value" type="v" direction="in"/>
+    </method>
+    <signal name="PropertiesChanged">
+      <arg name="interface_name" type="s"/>
+      <arg name="changed_properties" type="a{sv}"/>
+      <arg name="invalidated_properties" type="as"/>
+    </signal>
+  </interface>
+  <interface name="org.freedesktop.DBus.Introspectable">
+    <method name="Introspect">
+      <arg name="data" type="s" direction="out"/>
+    </method>
+  </interface>
+</node>`
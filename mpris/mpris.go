@@ -0,0 +1,262 @@
+package mpris
+
+import (
+	"context"
+	"fmt"
+	"hash/fnv"
+	"log"
+	"os"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"volumgui/actions"
+	"volumgui/client"
+
+	"github.com/godbus/dbus/v5"
+	"github.com/godbus/dbus/v5/introspect"
+)
+
+const (
+	BusName         = "org.mpris.MediaPlayer2.volumgui"
+	ObjectPath      = dbus.ObjectPath("/org/mpris/MediaPlayer2")
+	rootInterface   = "org.mpris.MediaPlayer2"
+	playerInterface = "org.mpris.MediaPlayer2.Player"
+	propsInterface  = "org.freedesktop.DBus.Properties"
+	noTrack         = dbus.ObjectPath("/org/mpris/MediaPlayer2/TrackList/NoTrack")
+	trackPrefix     = "/org/volumgui/track/"
+	actionTimeout   = 5 * time.Second
+	seekTolerance   = 2 * time.Second // position jumps beyond this are announced as Seeked
+)
+
+// Mpris exposes the player as an MPRIS2 media player so desktop widgets
+// and media keys can control it
+type Mpris struct {
+	Conn     *dbus.Conn
+	Hub      *client.StateHub
+	Runner   *actions.Runner
+	ArtBase  string // prefixed to album art paths like /albumart?path=...
+	DoneChan <-chan bool
+	InfoLog  *log.Logger
+	ErrorLog *log.Logger
+	mu       sync.Mutex
+	emitted  map[string]dbus.Variant // player properties last announced
+}
+
+func NewMpris(conn *dbus.Conn, hub *client.StateHub, runner *actions.Runner, art_base string, done_chan <-chan bool, info_log *log.Logger, error_log *log.Logger) *Mpris {
+	return &Mpris{
+		Conn:     conn,
+		Hub:      hub,
+		Runner:   runner,
+		ArtBase:  strings.TrimSuffix(art_base, "/"),
+		DoneChan: done_chan,
+		InfoLog:  info_log,
+		ErrorLog: error_log,
+	}
+}
+
+// Start exports the interfaces and takes the bus name, a second
+// instance gets a name with its pid as the spec suggests
+func (m *Mpris) Start() error {
+	exports := map[string]interface{}{
+		rootInterface:                         root{m},
+		playerInterface:                       player{m},
+		propsInterface:                        properties{m},
+		"org.freedesktop.DBus.Introspectable": introspect.Introspectable(introspection),
+	}
+	// a Seek method would clash with io.Seeker
+	methods := map[string]string{"SeekBy": "Seek"}
+	for iface, v := range exports {
+		if err := m.Conn.ExportWithMap(v, methods, ObjectPath, iface); err != nil {
+			return fmt.Errorf("mpris: export %s: %w", iface, err)
+		}
+	}
+
+	for _, name := range []string{BusName, BusName + ".instance" + strconv.Itoa(os.Getpid())} {
+		reply, err := m.Conn.RequestName(name, dbus.NameFlagDoNotQueue)
+		if err != nil {
+			return fmt.Errorf("mpris: %w", err)
+		}
+		if reply == dbus.RequestNameReplyPrimaryOwner {
+			m.InfoLog.Printf("mpris: registered as %s", name)
+			return nil
+		}
+	}
+	return fmt.Errorf("mpris: %s is taken", BusName)
+}
+
+// Run announces property changes until DoneChan is closed
+func (m *Mpris) Run() {
+	states := m.Hub.Subscribe()
+	defer m.Hub.Unsubscribe(states)
+	conns := m.Hub.SubscribeConn()
+	defer m.Hub.UnsubscribeConn(conns)
+
+	var previous client.State
+	var received time.Time
+	for {
+		select {
+		case state := <-states:
+			if m.seeked(previous, received, state) {
+				m.emit(playerInterface+".Seeked", int64(state.Seek)*1000)
+			}
+			previous, received = state, time.Now()
+			m.announce()
+		case <-conns:
+			m.announce()
+		case <-m.DoneChan:
+			m.Conn.Close()
+			return
+		}
+	}
+}
+
+// seeked reports whether the position jumped instead of advancing
+func (m *Mpris) seeked(previous client.State, received time.Time, state client.State) bool {
+	if received.IsZero() || previous.Uri != state.Uri || state.Duration == 0 {
+		return false
+	}
+	expected := previous.Elapsed()
+	if previous.Status == "play" {
+		expected += time.Since(received)
+	}
+	jump := state.Elapsed() - expected
+	return jump > seekTolerance || jump < -seekTolerance
+}
+
+// announce emits PropertiesChanged with the player properties that
+// changed, the position is left out as the spec requires
+func (m *Mpris) announce() {
+	current := m.playerProperties(m.Hub.Current())
+	delete(current, "Position")
+
+	m.mu.Lock()
+	changed := make(map[string]dbus.Variant)
+	for name, value := range current {
+		if previous, ok := m.emitted[name]; !ok || !reflect.DeepEqual(previous.Value(), value.Value()) {
+			changed[name] = value
+		}
+	}
+	m.emitted = current
+	m.mu.Unlock()
+
+	if len(changed) > 0 {
+		m.emit(propsInterface+".PropertiesChanged", playerInterface, changed, []string{})
+	}
+}
+
+func (m *Mpris) emit(signal string, values ...interface{}) {
+	if err := m.Conn.Emit(ObjectPath, signal, values...); err != nil {
+		m.ErrorLog.Printf("mpris: %s: %v", signal, err)
+	}
+}
+
+// run runs the action on behalf of a dbus call
+func (m *Mpris) run(action string) *dbus.Error {
+	a, err := actions.Parse(action)
+	if err != nil {
+		return dbus.MakeFailedError(err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), actionTimeout)
+	defer cancel()
+	if err := m.Runner.Run(ctx, a); err != nil {
+		m.ErrorLog.Printf("mpris: %v", err)
+		return dbus.MakeFailedError(err)
+	}
+	return nil
+}
+
+func (m *Mpris) rootProperties() map[string]dbus.Variant {
+	return map[string]dbus.Variant{
+		"CanQuit":             dbus.MakeVariant(false),
+		"CanRaise":            dbus.MakeVariant(false),
+		"HasTrackList":        dbus.MakeVariant(false),
+		"Identity":            dbus.MakeVariant("volumgui"),
+		"SupportedUriSchemes": dbus.MakeVariant([]string{}),
+		"SupportedMimeTypes":  dbus.MakeVariant([]string{}),
+	}
+}
+
+func (m *Mpris) playerProperties(state client.State) map[string]dbus.Variant {
+	connected := m.Hub.Conn() == client.Connected
+	return map[string]dbus.Variant{
+		"PlaybackStatus": dbus.MakeVariant(playbackStatus(state)),
+		"LoopStatus":     dbus.MakeVariant(loopStatus(state)),
+		"Rate":           dbus.MakeVariant(1.0),
+		"MinimumRate":    dbus.MakeVariant(1.0),
+		"MaximumRate":    dbus.MakeVariant(1.0),
+		"Shuffle":        dbus.MakeVariant(state.Random),
+		"Metadata":       dbus.MakeVariant(m.metadata(state)),
+		"Volume":         dbus.MakeVariant(float64(state.Volume) / 100),
+		"Position":       dbus.MakeVariant(int64(state.Seek) * 1000),
+		"CanGoNext":      dbus.MakeVariant(connected),
+		"CanGoPrevious":  dbus.MakeVariant(connected),
+		"CanPlay":        dbus.MakeVariant(connected && state.Uri != ""),
+		"CanPause":       dbus.MakeVariant(connected && state.Uri != ""),
+		"CanSeek":        dbus.MakeVariant(connected && state.Duration > 0),
+		"CanControl":     dbus.MakeVariant(true),
+	}
+}
+
+func (m *Mpris) metadata(state client.State) map[string]dbus.Variant {
+	metadata := map[string]dbus.Variant{
+		"mpris:trackid": dbus.MakeVariant(trackId(state.Uri)),
+	}
+	if state.Duration > 0 {
+		metadata["mpris:length"] = dbus.MakeVariant(state.Length().Microseconds())
+	}
+	if state.Title != "" {
+		metadata["xesam:title"] = dbus.MakeVariant(state.Title)
+	}
+	if state.Artist != "" {
+		metadata["xesam:artist"] = dbus.MakeVariant([]string{state.Artist})
+	}
+	if state.Album != "" {
+		metadata["xesam:album"] = dbus.MakeVariant(state.Album)
+	}
+	if state.Uri != "" {
+		metadata["xesam:url"] = dbus.MakeVariant(state.Uri)
+	}
+	if art := state.AlbumArt; art != "" {
+		if strings.HasPrefix(art, "/") {
+			art = m.ArtBase + art
+		}
+		metadata["mpris:artUrl"] = dbus.MakeVariant(art)
+	}
+	return metadata
+}
+
+func playbackStatus(state client.State) string {
+	switch state.Status {
+	case "play":
+		return "Playing"
+	case "pause":
+		return "Paused"
+	default:
+		return "Stopped"
+	}
+}
+
+func loopStatus(state client.State) string {
+	switch {
+	case state.RepeatSingle:
+		return "Track"
+	case state.Repeat:
+		return "Playlist"
+	default:
+		return "None"
+	}
+}
+
+// trackId derives an object path from the uri, uris contain characters
+// object paths don't allow
+func trackId(uri string) dbus.ObjectPath {
+	if uri == "" {
+		return noTrack
+	}
+	h := fnv.New64a()
+	h.Write([]byte(uri))
+	return dbus.ObjectPath(trackPrefix + strconv.FormatUint(h.Sum64(), 16))
+}
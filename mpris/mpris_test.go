@@ -0,0 +1,244 @@
+package mpris
+
+import (
+	"bufio"
+	"context"
+	"io"
+	"log"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"volumgui/actions"
+	"volumgui/client"
+
+	"github.com/godbus/dbus/v5"
+)
+
+const busConfig = `<!DOCTYPE busconfig PUBLIC "-//freedesktop//DTD D-Bus Bus Configuration 1.0//EN"
+ "http://www.freedesktop.org/standards/dbus/1.0/busconfig.dtd">
+<busconfig>
+  <type>session</type>
+  <listen>unix:dir=%s</listen>
+  <auth>EXTERNAL</auth>
+  <policy context="default">
+    <allow send_destination="*" eavesdrop="true"/>
+    <allow eavesdrop="true"/>
+    <allow own="*"/>
+  </policy>
+</busconfig>`
+
+// startBus runs a private dbus-daemon and returns its address
+func startBus(t *testing.T) string {
+	t.Helper()
+	if _, err := exec.LookPath("dbus-daemon"); err != nil {
+		t.Skip("dbus-daemon not installed")
+	}
+	dir := t.TempDir()
+	config := filepath.Join(dir, "bus.conf")
+	if err := os.WriteFile(config, []byte(strings.Replace(busConfig, "%s", dir, 1)), 0644); err != nil {
+		t.Fatal(err)
+	}
+	daemon := exec.Command("dbus-daemon", "--config-file="+config, "--nofork", "--print-address")
+	out, err := daemon.StdoutPipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := daemon.Start(); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		daemon.Process.Kill()
+		daemon.Wait()
+	})
+	address, err := bufio.NewReader(out).ReadString('\n')
+	if err != nil {
+		t.Fatal(err)
+	}
+	return strings.TrimSpace(address)
+}
+
+func connect(t *testing.T, address string) *dbus.Conn {
+	t.Helper()
+	conn, err := dbus.Connect(address)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return conn
+}
+
+// client recording its calls
+type callClient struct {
+	client.ClientInterface
+	calls chan string
+}
+
+func (c *callClient) record(call string) error { c.calls <- call; return nil }
+
+func (c *callClient) Play(ctx context.Context) error  { return c.record("play") }
+func (c *callClient) Pause(ctx context.Context) error { return c.record("pause") }
+func (c *callClient) Next(ctx context.Context) error  { return c.record("next") }
+func (c *callClient) SeekBy(ctx context.Context, offset time.Duration) error {
+	return c.record("seek by " + offset.String())
+}
+func (c *callClient) Seek(ctx context.Context, position time.Duration) error {
+	return c.record("seek " + position.String())
+}
+func (c *callClient) SetVolume(ctx context.Context, volume int, mute bool) error {
+	return c.record("volume " + strconv.Itoa(volume))
+}
+func (c *callClient) SetRepeatSingle(ctx context.Context, single bool) error {
+	return c.record("repeat single")
+}
+
+func expectCall(t *testing.T, c *callClient, want string) {
+	t.Helper()
+	select {
+	case got := <-c.calls:
+		if got != want {
+			t.Fatalf("expected %s, got %s", want, got)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatalf("timed out waiting for %s", want)
+	}
+}
+
+func Test_Mpris(t *testing.T) {
+	address := startBus(t)
+
+	state_chan := make(chan client.State)
+	done_chan := make(chan bool)
+	hub_done := make(chan bool)
+	defer close(hub_done)
+	hub := client.NewStateHub(state_chan, nil, hub_done)
+	go hub.Run()
+
+	c := callClient{calls: make(chan string, 4)}
+	logger := log.New(io.Discard, "", 0)
+	runner := actions.NewRunner(&c, hub.Current, nil, logger, logger)
+	m := NewMpris(connect(t, address), hub, runner, "http://volumio.local/", done_chan, logger, logger)
+	if err := m.Start(); err != nil {
+		t.Fatal(err)
+	}
+	stopped := make(chan bool)
+	go func() {
+		m.Run()
+		close(stopped)
+	}()
+	defer func() {
+		close(done_chan)
+		<-stopped
+	}()
+
+	desktop := connect(t, address)
+	defer desktop.Close()
+	signals := make(chan *dbus.Signal, 16)
+	desktop.Signal(signals)
+	if err := desktop.AddMatchSignal(dbus.WithMatchObjectPath(ObjectPath)); err != nil {
+		t.Fatal(err)
+	}
+
+	state := client.State{
+		Status: "play", Title: "Strange Brew", Artist: "Cream", Album: "Disraeli Gears",
+		AlbumArt: "/albumart?path=cream", Uri: "mnt/USB/cream/strange-brew.flac",
+		Duration: 166, Seek: 30000, Volume: 45,
+	}
+	state_chan <- state
+
+	// the change is announced
+	changed := waitChanged(t, signals, "PlaybackStatus")
+	if status := changed["PlaybackStatus"].Value(); status != "Playing" {
+		t.Fatalf("unexpected status %v", status)
+	}
+	metadata := changed["Metadata"].Value().(map[string]dbus.Variant)
+	if metadata["xesam:title"].Value() != "Strange Brew" || metadata["mpris:length"].Value() != int64(166000000) ||
+		metadata["mpris:artUrl"].Value() != "http://volumio.local/albumart?path=cream" {
+		t.Fatalf("unexpected metadata %v", metadata)
+	}
+
+	player := desktop.Object(BusName, ObjectPath)
+	volume, err := player.GetProperty(playerInterface + ".Volume")
+	if err != nil || volume.Value() != 0.45 {
+		t.Fatalf("unexpected volume %v %v", volume, err)
+	}
+	position, err := player.GetProperty(playerInterface + ".Position")
+	if err != nil || position.Value().(int64) < 30000000 {
+		t.Fatalf("unexpected position %v %v", position, err)
+	}
+
+	calls := []struct {
+		method string
+		args   []interface{}
+		want   string
+	}{
+		{"PlayPause", nil, "pause"},
+		{"Next", nil, "next"},
+		{"Seek", []interface{}{int64(-10000000)}, "seek by -10s"},
+		{"SetPosition", []interface{}{trackId(state.Uri), int64(90000000)}, "seek 1m30s"},
+	}
+	for _, call := range calls {
+		if err := player.Call(playerInterface+"."+call.method, 0, call.args...).Err; err != nil {
+			t.Fatalf("%s: %v", call.method, err)
+		}
+		expectCall(t, &c, call.want)
+	}
+	if err := player.SetProperty(playerInterface+".Volume", dbus.MakeVariant(0.8)); err != nil {
+		t.Fatal(err)
+	}
+	expectCall(t, &c, "volume 80")
+	if err := player.SetProperty(playerInterface+".LoopStatus", dbus.MakeVariant("Track")); err != nil {
+		t.Fatal(err)
+	}
+	expectCall(t, &c, "repeat single")
+
+	// unchanged properties aren't announced again, a jump is a seek
+	state.Status, state.Seek = "pause", 120000
+	state_chan <- state
+	if seeked := waitSignal(t, signals, playerInterface+".Seeked"); seeked.Body[0] != int64(120000000) {
+		t.Fatalf("unexpected seek %v", seeked.Body)
+	}
+	changed = waitChanged(t, signals, "PlaybackStatus")
+	if len(changed) != 1 || changed["PlaybackStatus"].Value() != "Paused" {
+		t.Fatalf("unexpected changes %v", changed)
+	}
+}
+
+func waitSignal(t *testing.T, signals chan *dbus.Signal, name string) *dbus.Signal {
+	t.Helper()
+	timeout := time.After(5 * time.Second)
+	for {
+		select {
+		case signal := <-signals:
+			if signal.Name == name {
+				return signal
+			}
+		case <-timeout:
+			t.Fatalf("no %s signal", name)
+		}
+	}
+}
+
+// waitChanged returns the changed properties of the first
+// PropertiesChanged signal changing property
+func waitChanged(t *testing.T, signals chan *dbus.Signal, property string) map[string]dbus.Variant {
+	t.Helper()
+	timeout := time.After(5 * time.Second)
+	for {
+		select {
+		case signal := <-signals:
+			if signal.Name != propsInterface+".PropertiesChanged" {
+				continue
+			}
+			changed := signal.Body[1].(map[string]dbus.Variant)
+			if _, ok := changed[property]; ok {
+				return changed
+			}
+		case <-timeout:
+			t.Fatalf("no change of %s announced", property)
+		}
+	}
+}
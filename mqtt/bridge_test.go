@@ -0,0 +1,199 @@
+package mqtt
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"log"
+	"log/slog"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"volumgui/actions"
+	"volumgui/client"
+
+	broker "github.com/mochi-mqtt/server/v2"
+	"github.com/mochi-mqtt/server/v2/hooks/auth"
+	"github.com/mochi-mqtt/server/v2/listeners"
+	"github.com/mochi-mqtt/server/v2/packets"
+)
+
+// client recording its calls
+type callClient struct {
+	client.ClientInterface
+	calls chan string
+}
+
+func (c *callClient) Next(ctx context.Context) error { c.calls <- "next"; return nil }
+func (c *callClient) SetVolume(ctx context.Context, volume int, mute bool) error {
+	c.calls <- "volume " + strconv.Itoa(volume)
+	return nil
+}
+func (c *callClient) Mute(ctx context.Context) error { c.calls <- "mute"; return nil }
+
+// embedded broker recording the messages published by the bridge
+type testBroker struct {
+	*broker.Server
+	mu       sync.Mutex
+	messages map[string][]string
+	received chan string
+}
+
+func newTestBroker(t *testing.T) (*testBroker, string) {
+	t.Helper()
+	b := testBroker{
+		Server: broker.New(&broker.Options{
+			InlineClient: true,
+			Logger:       slog.New(slog.NewTextHandler(io.Discard, nil)),
+		}),
+		messages: make(map[string][]string),
+		received: make(chan string, 64),
+	}
+	if err := b.AddHook(new(auth.AllowHook), nil); err != nil {
+		t.Fatal(err)
+	}
+	tcp := listeners.NewTCP(listeners.Config{ID: "test", Address: "127.0.0.1:0"})
+	if err := b.AddListener(tcp); err != nil {
+		t.Fatal(err)
+	}
+	if err := b.Serve(); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { b.Close() })
+
+	err := b.Subscribe("#", 1, func(_ *broker.Client, _ packets.Subscription, pk packets.Packet) {
+		b.mu.Lock()
+		b.messages[pk.TopicName] = append(b.messages[pk.TopicName], string(pk.Payload))
+		b.mu.Unlock()
+		b.received <- pk.TopicName
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &b, "tcp://" + tcp.Address()
+}
+
+// wait returns the payloads of topic once it got count of them
+func (b *testBroker) wait(t *testing.T, topic string, count int) []string {
+	t.Helper()
+	timeout := time.After(5 * time.Second)
+	for {
+		b.mu.Lock()
+		payloads := b.messages[topic]
+		b.mu.Unlock()
+		if len(payloads) >= count {
+			return payloads
+		}
+		select {
+		case <-b.received:
+		case <-timeout:
+			t.Fatalf("timed out waiting for %d messages on %s, got %v", count, topic, payloads)
+		}
+	}
+}
+
+func expectCall(t *testing.T, c *callClient, want string) {
+	t.Helper()
+	select {
+	case got := <-c.calls:
+		if got != want {
+			t.Fatalf("expected %s, got %s", want, got)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatalf("timed out waiting for %s", want)
+	}
+}
+
+func Test_Bridge(t *testing.T) {
+	b, address := newTestBroker(t)
+
+	state_chan := make(chan client.State)
+	hub_done := make(chan bool)
+	defer close(hub_done)
+	hub := client.NewStateHub(state_chan, nil, hub_done)
+	go hub.Run()
+
+	c := callClient{calls: make(chan string, 4)}
+	logger := log.New(io.Discard, "", 0)
+	runner := actions.NewRunner(&c, hub.Current, nil, logger, logger)
+	done_chan := make(chan bool)
+	bridge, err := NewBridge(Config{Broker: address, Prefix: "den", Discovery: DefaultDiscovery}, hub, runner, done_chan, logger, logger)
+	if err != nil {
+		t.Fatal(err)
+	}
+	stopped := make(chan bool)
+	go func() {
+		bridge.Run()
+		close(stopped)
+	}()
+
+	if online := b.wait(t, "den/availability", 1); online[0] != "online" {
+		t.Fatalf("unexpected availability %v", online)
+	}
+	var config map[string]any
+	if err := json.Unmarshal([]byte(b.wait(t, "homeassistant/number/den/volume/config", 1)[0]), &config); err != nil {
+		t.Fatal(err)
+	}
+	if config["command_topic"] != "den/volume/set" || config["state_topic"] != "den/state" {
+		t.Fatalf("unexpected discovery config %v", config)
+	}
+
+	// the state is published on connection, seek only changes are not
+	b.wait(t, "den/state", 1)
+	state_chan <- client.State{Title: "Tales of Brave Ulysses", Status: "play", Seek: 1000}
+	b.wait(t, "den/state", 2)
+	state_chan <- client.State{Title: "Tales of Brave Ulysses", Status: "play", Seek: 2000}
+	state_chan <- client.State{Title: "Tales of Brave Ulysses", Status: "pause", Seek: 2500}
+	states := b.wait(t, "den/state", 3)
+	var last status
+	if err := json.Unmarshal([]byte(states[len(states)-1]), &last); err != nil {
+		t.Fatal(err)
+	}
+	if last.Status != "pause" || last.Seek != 2500 || last.Conn != "connected" || len(states) != 3 {
+		t.Fatalf("unexpected states %v", states)
+	}
+
+	for _, m := range []struct{ topic, payload, call string }{
+		{"den/command", "next", "next"},
+		{"den/volume/set", "42.0", "volume 42"},
+		{"den/mute/set", "ON", "mute"},
+	} {
+		if err := b.Publish(m.topic, []byte(m.payload), false, 1); err != nil {
+			t.Fatal(err)
+		}
+		expectCall(t, &c, m.call)
+	}
+
+	close(done_chan)
+	<-stopped
+	if availability := b.wait(t, "den/availability", 2); availability[1] != "offline" {
+		t.Fatalf("unexpected availability %v", availability)
+	}
+}
+
+func Test_BridgeAction(t *testing.T) {
+	b := Bridge{Config: Config{Prefix: DefaultPrefix}}
+	tests := []struct {
+		topic, payload, want string
+	}{
+		{commandTopic, "seek +10s", "seek +10s"},
+		{volumeTopic, "35", "volume 35"},
+		{muteTopic, "OFF", "mute off"},
+		{randomTopic, "ON", "random on"},
+		{repeatTopic, "ON", "repeat all"},
+		{repeatTopic, "OFF", "repeat off"},
+	}
+	for _, test := range tests {
+		a, err := b.action(test.topic, test.payload)
+		if err != nil || a.String() != test.want {
+			t.Errorf("%s %q: expected %s, got %s %v", test.topic, test.payload, test.want, a, err)
+		}
+	}
+	for _, test := range [][2]string{{commandTopic, "quit"}, {commandTopic, "dance"}, {volumeTopic, "loud"}, {"eject", "now"}} {
+		if _, err := b.action(test[0], test[1]); err == nil {
+			t.Errorf("%s %q: expected an error", test[0], test[1])
+		}
+	}
+}
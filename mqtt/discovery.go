@@ -0,0 +1,84 @@
+package mqtt
+
+import (
+	"encoding/json"
+	"strings"
+)
+
+// home assistant has no mqtt media player, the player shows up as one
+// device with an entity per control
+type entity struct {
+	component string // sensor, number, switch or button
+	id        string
+	name      string
+	icon      string
+	value     string // template of the state, "" for buttons
+	command   string // command topic below the prefix
+	press     string // action of a button
+	extra     map[string]any
+}
+
+var entities = []entity{
+	{component: "sensor", id: "status", name: "Status", icon: "mdi:play-pause", value: "{{ value_json.status }}"},
+	{component: "sensor", id: "title", name: "Title", icon: "mdi:music", value: "{{ value_json.title }}"},
+	{component: "sensor", id: "artist", name: "Artist", icon: "mdi:account-music", value: "{{ value_json.artist }}"},
+	{component: "sensor", id: "album", name: "Album", icon: "mdi:album", value: "{{ value_json.album }}"},
+	{component: "number", id: "volume", name: "Volume", icon: "mdi:volume-high", value: "{{ value_json.volume }}", command: volumeTopic,
+		extra: map[string]any{"min": 0, "max": 100, "step": 1, "mode": "slider"}},
+	{component: "switch", id: "mute", name: "Mute", icon: "mdi:volume-off", value: "{{ 'ON' if value_json.mute else 'OFF' }}", command: muteTopic},
+	{component: "switch", id: "random", name: "Shuffle", icon: "mdi:shuffle", value: "{{ 'ON' if value_json.random else 'OFF' }}", command: randomTopic},
+	{component: "switch", id: "repeat", name: "Repeat", icon: "mdi:repeat", value: "{{ 'ON' if value_json.repeat or value_json.repeatSingle else 'OFF' }}", command: repeatTopic},
+	{component: "button", id: "toggle", name: "Play/Pause", icon: "mdi:play-pause", command: commandTopic, press: "toggle"},
+	{component: "button", id: "next", name: "Next", icon: "mdi:skip-next", command: commandTopic, press: "next"},
+	{component: "button", id: "prev", name: "Previous", icon: "mdi:skip-previous", command: commandTopic, press: "prev"},
+	{component: "button", id: "stop", name: "Stop", icon: "mdi:stop", command: commandTopic, press: "stop"},
+}
+
+// discovery returns the config topics and payloads of the entities
+func (b *Bridge) discovery() map[string][]byte {
+	node := strings.ReplaceAll(b.Config.Prefix, "/", "_")
+	device := map[string]any{
+		"identifiers":  []string{node},
+		"name":         "volumgui " + b.Config.Prefix,
+		"manufacturer": "volumgui",
+		"model":        "Volumio",
+	}
+	// the entities are unavailable while volumgui or volumio is gone
+	availability := []map[string]string{
+		{"topic": b.topic(availabilityTopic)},
+		{"topic": b.topic(stateTopic), "value_template": "{{ 'online' if value_json.conn == 'connected' else 'offline' }}"},
+	}
+
+	configs := make(map[string][]byte)
+	for _, e := range entities {
+		config := map[string]any{
+			"name":              e.name,
+			"unique_id":         node + "_" + e.id,
+			"object_id":         node + "_" + e.id,
+			"icon":              e.icon,
+			"device":            device,
+			"availability":      availability,
+			"availability_mode": "all",
+		}
+		if e.value != "" {
+			config["state_topic"] = b.topic(stateTopic)
+			config["value_template"] = e.value
+		}
+		if e.command != "" {
+			config["command_topic"] = b.topic(e.command)
+		}
+		if e.press != "" {
+			config["payload_press"] = e.press
+		}
+		for key, value := range e.extra {
+			config[key] = value
+		}
+		payload, err := json.Marshal(config)
+		if err != nil {
+			panic(err)
+		}
+		topic := strings.Join([]string{b.Config.Discovery, e.component, node, e.id, "config"}, "/")
+		configs[topic] = payload
+	}
+	return configs
+}
@@ -0,0 +1,74 @@
+package multiplexer
+
+import "time"
+
+const DefaultDebounce = 20 * time.Millisecond
+
+// change of a button wired to an input of the shift registers, Index
+// counts the bits of all chips starting with the least significant bit
+// of the first byte read
+type ButtonEvent struct {
+	Index   int
+	Pressed bool
+	Time    time.Time // time the input changed
+}
+
+// Decoder diffs successive frames into button events, an input has to
+// keep its level for Debounce before the change is reported
+type Decoder struct {
+	Debounce  time.Duration
+	ActiveLow bool // inputs pulled up, pressed buttons read 0
+	stable    []byte
+	raw       []byte
+	changed   []time.Time // time each input last changed its raw level
+}
+
+func NewDecoder(debounce time.Duration) *Decoder {
+	return &Decoder{Debounce: debounce}
+}
+
+// Feed takes the frame read at t and returns the debounced changes, the
+// first frame sets the initial levels and frames of another length are
+// ignored
+func (d *Decoder) Feed(frame []byte, t time.Time) []ButtonEvent {
+	if d.stable == nil {
+		d.stable = append([]byte(nil), frame...)
+		d.raw = append([]byte(nil), frame...)
+		d.changed = make([]time.Time, len(frame)*8)
+		return nil
+	}
+	if len(frame) != len(d.stable) {
+		return nil
+	}
+
+	var events []ButtonEvent
+	for i := range frame {
+		for bit := 0; bit < 8; bit++ {
+			index := i*8 + bit
+			mask := byte(1) << bit
+			level := frame[i] & mask
+			if level != d.raw[i]&mask {
+				d.raw[i] ^= mask
+				d.changed[index] = t
+			}
+			if level == d.stable[i]&mask || t.Sub(d.changed[index]) < d.Debounce {
+				continue
+			}
+			d.stable[i] ^= mask
+			events = append(events, ButtonEvent{
+				Index:   index,
+				Pressed: (level != 0) != d.ActiveLow,
+				Time:    d.changed[index],
+			})
+		}
+	}
+	return events
+}
+
+// Pressed reports the debounced state of the input at index
+func (d *Decoder) Pressed(index int) bool {
+	if index < 0 || index/8 >= len(d.stable) {
+		return false
+	}
+	return (d.stable[index/8]&(1<<(index%8)) != 0) != d.ActiveLow
+}
@@ -0,0 +1,53 @@
+package multiplexer
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func Test_DecoderDebounce(t *testing.T) {
+	start := time.Unix(0, 0)
+	at := func(ms int) time.Time { return start.Add(time.Duration(ms) * time.Millisecond) }
+
+	d := NewDecoder(20 * time.Millisecond)
+	steps := []struct {
+		ms    int
+		frame []byte
+		want  []ButtonEvent
+	}{
+		{0, []byte{0x00, 0x00}, nil},
+		// contact bounce on input 0 is swallowed
+		{5, []byte{0x01, 0x00}, nil},
+		{10, []byte{0x00, 0x00}, nil},
+		{15, []byte{0x01, 0x00}, nil},
+		{30, []byte{0x01, 0x00}, nil},
+		{35, []byte{0x01, 0x00}, []ButtonEvent{{Index: 0, Pressed: true, Time: at(15)}}},
+		// input 15 and the release of input 0 in the same frame
+		{100, []byte{0x00, 0x80}, nil},
+		{120, []byte{0x00, 0x80}, []ButtonEvent{
+			{Index: 0, Pressed: false, Time: at(100)},
+			{Index: 15, Pressed: true, Time: at(100)},
+		}},
+		{200, []byte{0x00}, nil},
+	}
+	for _, step := range steps {
+		got := d.Feed(step.frame, at(step.ms))
+		if !reflect.DeepEqual(got, step.want) {
+			t.Fatalf("%d ms: expected %+v, got %+v", step.ms, step.want, got)
+		}
+	}
+	if !d.Pressed(15) || d.Pressed(0) {
+		t.Fatal("unexpected debounced state")
+	}
+}
+
+func Test_DecoderActiveLow(t *testing.T) {
+	d := NewDecoder(0)
+	d.ActiveLow = true
+	d.Feed([]byte{0xff}, time.Unix(0, 0))
+	events := d.Feed([]byte{0xfb}, time.Unix(1, 0))
+	if len(events) != 1 || events[0].Index != 2 || !events[0].Pressed {
+		t.Fatalf("unexpected events %+v", events)
+	}
+}
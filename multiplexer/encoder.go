@@ -0,0 +1,133 @@
+package multiplexer
+
+import "time"
+
+// valid gray code transitions indexed by previous<<2 | current state,
+// invalid ones (both inputs changed) count as no movement
+var quadrature = [16]int8{0, -1, 1, 0, 1, 0, 0, -1, -1, 0, 0, 1, 0, 1, -1, 0}
+
+// speed dependent multiplier, a detent within Within of the previous one
+// in the same direction counts Factor times
+type AccelStep struct {
+	Within time.Duration
+	Factor int
+}
+
+var DefaultAcceleration = []AccelStep{
+	{Within: 25 * time.Millisecond, Factor: 4},
+	{Within: 60 * time.Millisecond, Factor: 2},
+}
+
+// detents of an encoder, Delta includes the acceleration
+type EncoderEvent struct {
+	Encoder int // position in PISO.Encoders
+	Detents int
+	Delta   int
+	Time    time.Time
+}
+
+// Encoder decodes a quadrature encoder wired to inputs A and B, detents
+// are positive when A changes first. Its push switch is an ordinary
+// button reported through the gestures
+type Encoder struct {
+	A, B           int // input bits
+	StepsPerDetent int // 4 for full cycle, 2 for half cycle and 1 for quarter cycle encoders
+	Rest           uint8
+	Acceleration   []AccelStep // ascending by Within
+	state          uint8
+	count          int
+	started        bool
+	lastDetent     time.Time
+	lastDirection  int
+}
+
+// NewEncoder for a full cycle encoder with pull ups, resting with both
+// inputs high
+func NewEncoder(a, b int) *Encoder {
+	return &Encoder{
+		A:              a,
+		B:              b,
+		StepsPerDetent: 4,
+		Rest:           3,
+		Acceleration:   DefaultAcceleration,
+	}
+}
+
+func bit(frame []byte, index int) uint8 {
+	if index/8 >= len(frame) {
+		return 0
+	}
+	return (frame[index/8] >> (index % 8)) & 1
+}
+
+func (e *Encoder) resting(state uint8) bool {
+	switch e.StepsPerDetent {
+	case 1:
+		return true
+	case 2:
+		return state == e.Rest || state == e.Rest^3
+	default:
+		return state == e.Rest
+	}
+}
+
+// Feed takes a frame read at t and returns the detents it completes and
+// their accelerated delta, zero if none
+func (e *Encoder) Feed(frame []byte, t time.Time) (int, int) {
+	state := bit(frame, e.A)<<1 | bit(frame, e.B)
+	if !e.started {
+		e.state, e.started = state, true
+		return 0, 0
+	}
+	e.count += int(quadrature[e.state<<2|state])
+	e.state = state
+	if !e.resting(state) {
+		return 0, 0
+	}
+
+	// count the detent once at least half its steps were seen, bounces
+	// cancel out on the way
+	threshold := (e.StepsPerDetent + 1) / 2
+	detents := 0
+	switch {
+	case e.count >= threshold:
+		detents = 1
+	case e.count <= -threshold:
+		detents = -1
+	}
+	e.count = 0
+	if detents == 0 {
+		return 0, 0
+	}
+	return detents, e.accelerate(detents, t)
+}
+
+// accelerate scales detents by the speed of the turn
+func (e *Encoder) accelerate(detents int, t time.Time) int {
+	direction := 1
+	if detents < 0 {
+		direction = -1
+	}
+	factor := 1
+	if direction == e.lastDirection && !e.lastDetent.IsZero() {
+		since := t.Sub(e.lastDetent)
+		for _, step := range e.Acceleration {
+			if since <= step.Within {
+				factor = step.Factor
+				break
+			}
+		}
+	}
+	e.lastDetent = t
+	e.lastDirection = direction
+	return detents * factor
+}
+
+// mask clears the quadrature inputs, they are not buttons
+func (e *Encoder) mask(frame []byte) {
+	for _, index := range []int{e.A, e.B} {
+		if index/8 < len(frame) {
+			frame[index/8] &^= 1 << (index % 8)
+		}
+	}
+}
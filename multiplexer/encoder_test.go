@@ -0,0 +1,189 @@
+package multiplexer
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log"
+	"sync"
+	"testing"
+	"time"
+
+	"volumgui/client"
+)
+
+// frames for an encoder on bits 0 (A) and 1 (B), one detent per cycle,
+// A changes first when turning clockwise
+var (
+	clockwise        = []byte{0x03, 0x02, 0x00, 0x01, 0x03}
+	counterClockwise = []byte{0x03, 0x01, 0x00, 0x02, 0x03}
+)
+
+func feedEncoder(e *Encoder, states []byte, start time.Time, step time.Duration) (detents, delta int) {
+	for i, state := range states {
+		d, a := e.Feed([]byte{state}, start.Add(time.Duration(i)*step))
+		detents += d
+		delta += a
+	}
+	return detents, delta
+}
+
+func Test_EncoderDirection(t *testing.T) {
+	start := time.Unix(0, 0)
+	e := NewEncoder(0, 1)
+	if detents, _ := feedEncoder(e, clockwise, start, 100*time.Millisecond); detents != 1 {
+		t.Fatalf("expected one detent clockwise, got %d", detents)
+	}
+	if detents, _ := feedEncoder(e, counterClockwise[1:], start.Add(time.Second), 100*time.Millisecond); detents != -1 {
+		t.Fatalf("expected one detent counter clockwise, got %d", detents)
+	}
+	// bouncing between two states and back to rest is no detent
+	if detents, _ := feedEncoder(e, []byte{0x02, 0x03, 0x02, 0x03}, start.Add(2*time.Second), 100*time.Millisecond); detents != 0 {
+		t.Fatalf("bounce counted as %d detents", detents)
+	}
+	// a missed intermediate state still completes the detent
+	if detents, _ := feedEncoder(e, []byte{0x02, 0x00, 0x03}, start.Add(3*time.Second), 100*time.Millisecond); detents != 1 {
+		t.Fatalf("expected one detent with a missed state, got %d", detents)
+	}
+}
+
+func Test_EncoderAcceleration(t *testing.T) {
+	start := time.Unix(0, 0)
+	e := NewEncoder(0, 1)
+	e.Feed([]byte{0x03}, start)
+
+	turns := append(append(append([]byte{}, clockwise[1:]...), clockwise[1:]...), clockwise[1:]...)
+	detents, delta := feedEncoder(e, turns, start.Add(time.Second), 2*time.Millisecond)
+	// the first detent is slow, the others within 25ms of the previous one
+	if detents != 3 || delta != 1+4+4 {
+		t.Fatalf("expected 3 detents with delta 9, got %d and %d", detents, delta)
+	}
+}
+
+func Test_PisoEncoder(t *testing.T) {
+	frames := [][]byte{{0x03}}
+	for _, state := range clockwise[1:] {
+		frames = append(frames, []byte{state})
+	}
+	bus := NewFakeSPI(frames...)
+	piso, _ := newTestPiso(t, 1, 500, bus)
+	piso.Decoder.Debounce = 0
+	piso.Encoders = append(piso.Encoders, NewEncoder(0, 1))
+	piso.Start()
+	defer close(piso.DoneChan)
+
+	select {
+	case event := <-piso.EncoderChan:
+		if event.Encoder != 0 || event.Detents != 1 {
+			t.Fatalf("unexpected event %+v", event)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("timed out waiting for encoder event")
+	}
+	// quadrature inputs are no buttons
+	select {
+	case gesture := <-piso.GestureChan:
+		t.Fatalf("unexpected gesture %+v", gesture)
+	case <-time.After(50 * time.Millisecond):
+	}
+}
+
+// client recording the volumes it was set to
+type volumeClient struct {
+	client.ClientInterface
+	mu      sync.Mutex
+	volumes []int
+	block   chan bool // answers wait until it is closed, optional
+	err     error     // returned by every call
+}
+
+func (c *volumeClient) SetVolume(ctx context.Context, volume int, mute bool) error {
+	c.mu.Lock()
+	c.volumes = append(c.volumes, volume)
+	c.mu.Unlock()
+	if c.block != nil {
+		<-c.block
+	}
+	return c.err
+}
+
+func (c *volumeClient) calls() []int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return append([]int(nil), c.volumes...)
+}
+
+func Test_VolumeKnob(t *testing.T) {
+	c := volumeClient{}
+	knob := NewVolumeKnob(&c, func() client.State { return client.State{Volume: 50} }, log.New(io.Discard, "", 0))
+	knob.Interval = 100 * time.Millisecond
+
+	events := make(chan EncoderEvent)
+	done := make(chan bool)
+	go knob.Run(events, done)
+	defer close(done)
+
+	// spinning the knob fast
+	for i := 0; i < 30; i++ {
+		events <- EncoderEvent{Detents: 1, Delta: 1}
+		time.Sleep(5 * time.Millisecond)
+	}
+	time.Sleep(300 * time.Millisecond)
+
+	volumes := c.calls()
+	if len(volumes) == 0 || len(volumes) > 4 {
+		t.Fatalf("expected a few coalesced calls, got %v", volumes)
+	}
+	if last := volumes[len(volumes)-1]; last != 100 {
+		t.Fatalf("expected the volume to end at 100, got %v", volumes)
+	}
+}
+
+func Test_VolumeKnobBusyPlayer(t *testing.T) {
+	c := volumeClient{block: make(chan bool)}
+	knob := NewVolumeKnob(&c, func() client.State { return client.State{Volume: 50} }, log.New(io.Discard, "", 0))
+	knob.Interval = 10 * time.Millisecond
+
+	events := make(chan EncoderEvent)
+	done := make(chan bool)
+	go knob.Run(events, done)
+	defer close(done)
+
+	// the first turn is sent and the player does not answer
+	for i := 0; i < 10; i++ {
+		select {
+		case events <- EncoderEvent{Detents: 1, Delta: 1}:
+		case <-time.After(time.Second):
+			t.Fatal("knob blocked on the player")
+		}
+	}
+	close(c.block)
+	time.Sleep(200 * time.Millisecond)
+
+	volumes := c.calls()
+	if len(volumes) != 2 || volumes[0] != 52 || volumes[1] != 70 {
+		t.Fatalf("expected the turns to be sent once the player answered, got %v", volumes)
+	}
+}
+
+func Test_VolumeKnobErrors(t *testing.T) {
+	c := volumeClient{err: client.ErrUnavailable}
+	knob := NewVolumeKnob(&c, func() client.State { return client.State{Volume: 50} }, log.New(io.Discard, "", 0))
+	errs := make(chan error, 1)
+	knob.ErrChan = errs
+
+	events := make(chan EncoderEvent)
+	done := make(chan bool)
+	go knob.Run(events, done)
+	defer close(done)
+
+	events <- EncoderEvent{Detents: 1, Delta: 1}
+	select {
+	case err := <-errs:
+		if !errors.Is(err, client.ErrUnavailable) {
+			t.Fatalf("expected ErrUnavailable, got %v", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("failed call was not reported")
+	}
+}
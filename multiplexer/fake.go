@@ -0,0 +1,110 @@
+package multiplexer
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+)
+
+var ErrClosed = errors.New("multiplexer: bus closed")
+
+// in-memory SPI bus returning scripted frames and recording the written
+// ones, the last scripted frame is repeated once the script ran out
+type FakeSPI struct {
+	mu      sync.Mutex
+	frames  [][]byte
+	last    []byte
+	reads   int
+	written [][]byte
+	closed  bool
+}
+
+func NewFakeSPI(frames ...[]byte) *FakeSPI {
+	return &FakeSPI{frames: frames}
+}
+
+// Push appends frames to the script
+func (s *FakeSPI) Push(frames ...[]byte) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.frames = append(s.frames, frames...)
+}
+
+func (s *FakeSPI) Receive(n int) ([]byte, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.closed {
+		return nil, ErrClosed
+	}
+	if len(s.frames) > 0 {
+		s.last, s.frames = s.frames[0], s.frames[1:]
+	}
+	s.reads++
+	data := make([]byte, n)
+	if s.last != nil && len(s.last) != n {
+		return nil, fmt.Errorf("multiplexer: scripted frame has %d bytes, read %d", len(s.last), n)
+	}
+	copy(data, s.last)
+	return data, nil
+}
+
+func (s *FakeSPI) Transmit(data []byte) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.closed {
+		return ErrClosed
+	}
+	s.written = append(s.written, append([]byte(nil), data...))
+	return nil
+}
+
+// Written returns the frames transmitted so far
+func (s *FakeSPI) Written() [][]byte {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([][]byte(nil), s.written...)
+}
+
+// Reads returns the number of frames received so far
+func (s *FakeSPI) Reads() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.reads
+}
+
+func (s *FakeSPI) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.closed = true
+	return nil
+}
+
+// in-memory pin counting the load pulses
+type FakePin struct {
+	mu     sync.Mutex
+	high   bool
+	pulses int // low to high transitions
+}
+
+func (p *FakePin) High() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if !p.high {
+		p.pulses++
+	}
+	p.high = true
+}
+
+func (p *FakePin) Low() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.high = false
+}
+
+func (p *FakePin) Pulses() int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.pulses
+}
@@ -0,0 +1,229 @@
+package multiplexer
+
+import (
+	"fmt"
+	"sort"
+	"time"
+)
+
+type GestureKind int
+
+const (
+	ShortPress  GestureKind = iota
+	LongPress               // held for the long threshold
+	RepeatPress             // still held after a long press, once per repeat interval
+	DoublePress             // second short press within the double threshold
+	Chord                   // several buttons pressed together
+)
+
+func (k GestureKind) String() string {
+	switch k {
+	case ShortPress:
+		return "short"
+	case LongPress:
+		return "long"
+	case RepeatPress:
+		return "repeat"
+	case DoublePress:
+		return "double"
+	case Chord:
+		return "chord"
+	default:
+		return fmt.Sprintf("GestureKind(%d)", int(k))
+	}
+}
+
+// ParseGestureKind is the inverse of GestureKind.String
+func ParseGestureKind(s string) (GestureKind, error) {
+	for k := ShortPress; k <= Chord; k++ {
+		if k.String() == s {
+			return k, nil
+		}
+	}
+	return 0, fmt.Errorf("multiplexer: unknown gesture %q", s)
+}
+
+type Gesture struct {
+	Kind    GestureKind
+	Buttons []int // the button, or the buttons of a chord in ascending order
+	Held    time.Duration
+	Count   int // repeats so far, for RepeatPress
+	Time    time.Time
+}
+
+// Index returns the button of a single button gesture
+func (g Gesture) Index() int {
+	return g.Buttons[0]
+}
+
+// Thresholds of a button, zero disables the gesture
+type Thresholds struct {
+	Long   time.Duration // hold time of a long press
+	Repeat time.Duration // interval of repeats after a long press
+	Double time.Duration // time after a release a second press is waited for
+}
+
+// double presses are off by default, they delay every short press
+var DefaultThresholds = Thresholds{Long: 800 * time.Millisecond}
+
+type buttonState struct {
+	down         bool
+	pressed      time.Time
+	chord        bool // pressed with other buttons, no single gesture follows
+	long         bool // long press sent
+	lastRepeat   time.Time
+	repeats      int
+	second       bool // second press of a double press
+	pending      bool // released short press waiting for a second one
+	released     time.Time
+	releasedHeld time.Duration
+}
+
+// Recognizer classifies button events into gestures, Feed handles the
+// events and Tick the gestures that depend on time passing
+type Recognizer struct {
+	Default Thresholds
+	Buttons map[int]Thresholds // per button thresholds overriding Default
+	states  map[int]*buttonState
+}
+
+func NewRecognizer(thresholds Thresholds) *Recognizer {
+	return &Recognizer{
+		Default: thresholds,
+		Buttons: make(map[int]Thresholds),
+		states:  make(map[int]*buttonState),
+	}
+}
+
+func (r *Recognizer) thresholds(index int) Thresholds {
+	if t, ok := r.Buttons[index]; ok {
+		return t
+	}
+	return r.Default
+}
+
+func (r *Recognizer) state(index int) *buttonState {
+	s, ok := r.states[index]
+	if !ok {
+		s = &buttonState{}
+		r.states[index] = s
+	}
+	return s
+}
+
+func single(kind GestureKind, index int, held time.Duration, t time.Time) Gesture {
+	return Gesture{Kind: kind, Buttons: []int{index}, Held: held, Time: t}
+}
+
+func (r *Recognizer) Feed(e ButtonEvent) []Gesture {
+	s := r.state(e.Index)
+	th := r.thresholds(e.Index)
+	if e.Pressed {
+		if s.down {
+			return nil
+		}
+		var gestures []Gesture
+		second := false
+		if s.pending {
+			if e.Time.Sub(s.released) <= th.Double {
+				second = true
+			} else {
+				gestures = append(gestures, single(ShortPress, e.Index, s.releasedHeld, s.released))
+			}
+		}
+		*s = buttonState{
+			down:         true,
+			pressed:      e.Time,
+			second:       second,
+			released:     s.released,
+			releasedHeld: s.releasedHeld,
+		}
+		return append(gestures, r.chord(e)...)
+	}
+
+	if !s.down {
+		return nil
+	}
+	s.down = false
+	held := e.Time.Sub(s.pressed)
+	switch {
+	case s.chord || s.long:
+		return nil
+	case s.second:
+		s.second = false
+		return []Gesture{single(DoublePress, e.Index, held, e.Time)}
+	case th.Double > 0:
+		s.pending = true
+		s.released = e.Time
+		s.releasedHeld = held
+		return nil
+	default:
+		return []Gesture{single(ShortPress, e.Index, held, e.Time)}
+	}
+}
+
+// a press while other buttons are held, before they turned into a long
+// press, makes a chord of all of them
+func (r *Recognizer) chord(e ButtonEvent) []Gesture {
+	buttons := []int{e.Index}
+	for index, s := range r.states {
+		if index != e.Index && s.down && !s.chord && !s.long {
+			buttons = append(buttons, index)
+		}
+	}
+	if len(buttons) == 1 {
+		return nil
+	}
+	sort.Ints(buttons)
+	var gestures []Gesture
+	for _, index := range buttons {
+		s := r.states[index]
+		if s.second {
+			// the completed first press of an interrupted double press
+			gestures = append(gestures, single(ShortPress, index, s.releasedHeld, s.released))
+		}
+		s.chord = true
+		s.second = false
+	}
+	return append(gestures, Gesture{Kind: Chord, Buttons: buttons, Time: e.Time})
+}
+
+func (r *Recognizer) Tick(now time.Time) []Gesture {
+	indexes := make([]int, 0, len(r.states))
+	for index := range r.states {
+		indexes = append(indexes, index)
+	}
+	sort.Ints(indexes)
+
+	var gestures []Gesture
+	for _, index := range indexes {
+		s := r.states[index]
+		th := r.thresholds(index)
+		if s.pending && now.Sub(s.released) > th.Double {
+			// no second press followed
+			s.pending = false
+			gestures = append(gestures, single(ShortPress, index, s.releasedHeld, s.released))
+		}
+		if !s.down || s.chord {
+			continue
+		}
+		held := now.Sub(s.pressed)
+		switch {
+		case !s.long && th.Long > 0 && held >= th.Long:
+			if s.second {
+				s.second = false
+				gestures = append(gestures, single(ShortPress, index, s.releasedHeld, s.released))
+			}
+			s.long = true
+			s.lastRepeat = now
+			gestures = append(gestures, single(LongPress, index, held, now))
+		case s.long && th.Repeat > 0 && now.Sub(s.lastRepeat) >= th.Repeat:
+			s.lastRepeat = now
+			s.repeats++
+			g := single(RepeatPress, index, held, now)
+			g.Count = s.repeats
+			gestures = append(gestures, g)
+		}
+	}
+	return gestures
+}
@@ -0,0 +1,114 @@
+package multiplexer
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+// script of button changes and ticks at millisecond offsets
+type gestureStep struct {
+	ms      int
+	index   int
+	pressed bool
+	tick    bool
+}
+
+func runGestures(r *Recognizer, steps []gestureStep) []Gesture {
+	start := time.Unix(0, 0)
+	var gestures []Gesture
+	for _, step := range steps {
+		t := start.Add(time.Duration(step.ms) * time.Millisecond)
+		if step.tick {
+			gestures = append(gestures, r.Tick(t)...)
+		} else {
+			gestures = append(gestures, r.Feed(ButtonEvent{Index: step.index, Pressed: step.pressed, Time: t})...)
+		}
+	}
+	return gestures
+}
+
+func press(ms, index int) gestureStep   { return gestureStep{ms: ms, index: index, pressed: true} }
+func release(ms, index int) gestureStep { return gestureStep{ms: ms, index: index} }
+func tick(ms int) gestureStep           { return gestureStep{ms: ms, tick: true} }
+
+func kinds(gestures []Gesture) []string {
+	var k []string
+	for _, g := range gestures {
+		k = append(k, g.Kind.String())
+	}
+	return k
+}
+
+func Test_Gestures(t *testing.T) {
+	tests := []struct {
+		name       string
+		thresholds Thresholds
+		steps      []gestureStep
+		want       []string
+	}{
+		{"short", DefaultThresholds, []gestureStep{press(0, 1), tick(100), release(200, 1), tick(1000)}, []string{"short"}},
+		{"long", DefaultThresholds, []gestureStep{press(0, 1), tick(500), tick(800), tick(900), release(1500, 1)}, []string{"long"}},
+		{"repeat", Thresholds{Long: 500 * time.Millisecond, Repeat: 200 * time.Millisecond},
+			[]gestureStep{press(0, 1), tick(500), tick(600), tick(700), tick(900), release(950, 1)},
+			[]string{"long", "repeat", "repeat"}},
+		{"double", Thresholds{Long: time.Second, Double: 300 * time.Millisecond},
+			[]gestureStep{press(0, 1), release(100, 1), tick(200), press(300, 1), release(400, 1), tick(1000)},
+			[]string{"double"}},
+		{"double too slow", Thresholds{Long: time.Second, Double: 300 * time.Millisecond},
+			[]gestureStep{press(0, 1), release(100, 1), tick(450), press(500, 1), release(600, 1), tick(1000)},
+			[]string{"short", "short"}},
+		{"short then long", Thresholds{Long: time.Second, Double: 300 * time.Millisecond},
+			[]gestureStep{press(0, 1), release(100, 1), press(300, 1), tick(1300), release(1400, 1)},
+			[]string{"short", "long"}},
+		{"chord", DefaultThresholds, []gestureStep{press(0, 1), press(50, 2), tick(1000), release(1100, 1), release(1100, 2)}, []string{"chord"}},
+		{"long is no chord", DefaultThresholds,
+			[]gestureStep{press(0, 1), tick(800), press(900, 2), release(1000, 2), release(1100, 1)},
+			[]string{"long", "short"}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got := kinds(runGestures(NewRecognizer(test.thresholds), test.steps))
+			if !reflect.DeepEqual(got, test.want) {
+				t.Fatalf("expected %v, got %v", test.want, got)
+			}
+		})
+	}
+}
+
+func Test_GestureDetails(t *testing.T) {
+	r := NewRecognizer(DefaultThresholds)
+	r.Buttons[4] = Thresholds{Long: 2 * time.Second}
+
+	gestures := runGestures(r, []gestureStep{press(0, 4), press(10, 2), release(100, 2), release(100, 4)})
+	if len(gestures) != 1 || !reflect.DeepEqual(gestures[0].Buttons, []int{2, 4}) {
+		t.Fatalf("unexpected chord %+v", gestures)
+	}
+
+	// button 4 uses its own long threshold
+	gestures = runGestures(r, []gestureStep{press(1000, 4), tick(1900), tick(3000), release(3100, 4)})
+	if len(gestures) != 1 || gestures[0].Kind != LongPress || gestures[0].Index() != 4 || gestures[0].Held != 2*time.Second {
+		t.Fatalf("unexpected gestures %+v", gestures)
+	}
+
+	if kind, err := ParseGestureKind("double"); err != nil || kind != DoublePress {
+		t.Fatalf("unexpected kind %v, %v", kind, err)
+	}
+}
+
+func Test_PisoGestures(t *testing.T) {
+	bus := NewFakeSPI([]byte{0x00}, []byte{0x04}, []byte{0x04}, []byte{0x04}, []byte{0x04}, []byte{0x00})
+	piso, _ := newTestPiso(t, 1, 200, bus)
+	piso.Decoder.Debounce = 0
+	piso.Start()
+	defer close(piso.DoneChan)
+
+	select {
+	case gesture := <-piso.GestureChan:
+		if gesture.Kind != ShortPress || gesture.Index() != 2 {
+			t.Fatalf("unexpected gesture %+v", gesture)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("timed out waiting for gesture")
+	}
+}
@@ -0,0 +1,14 @@
+package multiplexer
+
+// SPI bus the chained shift registers are clocked out on
+type SPI interface {
+	Receive(n int) ([]byte, error) // clock in n bytes
+	Transmit(data []byte) error    // clock out data
+	Close() error
+}
+
+// digital output pin
+type Pin interface {
+	High()
+	Low()
+}
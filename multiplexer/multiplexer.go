@@ -1,10 +1,10 @@
 package multiplexer
 
 import (
+	"errors"
+	"log"
 	"sync"
 	"time"
-
-	"github.com/stianeikeland/go-rpio/v4"
 )
 
 type ChipSelect uint8
@@ -15,89 +15,126 @@ const (
 	CE2
 )
 
+const eventBuffer = 64
+
 type PISO struct {
-	SH_LD      rpio.Pin
-	NDev       int         // number of chips
-	SpiDev     rpio.SpiDev // SPI0, SPI1, ...
-	ChipSel    ChipSelect  // CE0, CE1, (CE2)
-	LastData   []byte
-	interval   time.Duration
-	Wait       *sync.WaitGroup
-	NotifyChan chan bool
-	DoneChan   chan bool
+	SH_LD       Pin // low loads the parallel inputs, high shifts them out
+	NDev        int // number of chips
+	Bus         SPI
+	Decoder     *Decoder
+	Recognizer  *Recognizer
+	Encoders    []*Encoder
+	interval    time.Duration
+	Wait        *sync.WaitGroup
+	GestureChan chan Gesture
+	EncoderChan chan EncoderEvent
+	DoneChan    chan bool
+	ErrorLog    *log.Logger
 }
 
-// deep equal helper
-func dataEq(a, b []byte) bool {
-	if len(a) != len(b) {
-		return false
+func NewPiso(slhd Pin, ndev int, bus SPI, reads_per_sec int, wg *sync.WaitGroup, error_log *log.Logger) (*PISO, error) {
+	if ndev < 1 {
+		return nil, errors.New("multiplexer: at least one chip is needed")
 	}
-	for i := range a {
-		if a[i] != b[i] {
-			return false
-		}
+	if reads_per_sec < 1 {
+		return nil, errors.New("multiplexer: reads per second must be positive")
 	}
-
-	return true
-}
-
-func NewPiso(slhd rpio.Pin, ndev int, spidev rpio.SpiDev, cs ChipSelect, reads_per_sec int, wg *sync.WaitGroup) PISO {
 	doneChan := make(chan bool)
-	ntfyChan := make(chan bool)
-	// setup SPI
-	if err := rpio.SpiBegin(spidev); err != nil {
-		panic(err)
-	}
-	rpio.SpiChipSelect(uint8(cs))
-	rpio.SpiSpeed(5000000)
+	gestureChan := make(chan Gesture, eventBuffer)
+	encoderChan := make(chan EncoderEvent, eventBuffer)
 
-	interval := time.Duration(1.0 / float64(reads_per_sec))
 	piso := PISO{
-		SH_LD:      slhd,
-		NDev:       ndev,
-		SpiDev:     spidev,
-		ChipSel:    cs,
-		interval:   interval,
-		Wait:       wg,
-		DoneChan:   doneChan,
-		NotifyChan: ntfyChan,
+		SH_LD:       slhd,
+		NDev:        ndev,
+		Bus:         bus,
+		Decoder:     NewDecoder(DefaultDebounce),
+		Recognizer:  NewRecognizer(DefaultThresholds),
+		interval:    time.Second / time.Duration(reads_per_sec),
+		Wait:        wg,
+		DoneChan:    doneChan,
+		GestureChan: gestureChan,
+		EncoderChan: encoderChan,
+		ErrorLog:    error_log,
 	}
+	slhd.High()
 
-	return piso
+	return &piso, nil
 }
 
 // read once and return data
-func (p *PISO) Read() []byte {
-	defer p.Wait.Done()
+func (p *PISO) Read() ([]byte, error) {
+	// latch the inputs, then shift them out
+	p.SH_LD.Low()
+	p.SH_LD.High()
+	data, err := p.Bus.Receive(p.NDev)
+	if err != nil {
+		return nil, err
+	}
+
+	return data, nil
+}
 
-	data := make([]byte, p.NDev)
-	rpio.TogglePin(p.SH_LD)
-	read_time := time.Now()
-	data = rpio.SpiReceive(p.NDev)
-	if dataEq(data, p.LastData) {
-		// write to CallbackChan
-		p.LastData = data
+// read a frame and send the encoder turns and gestures it completes
+func (p *PISO) poll(t time.Time) {
+	data, err := p.Read()
+	if err != nil {
+		p.ErrorLog.Println(err)
+		return
+	}
+	for i, encoder := range p.Encoders {
+		detents, delta := encoder.Feed(data, t)
+		encoder.mask(data)
+		if detents == 0 {
+			continue
+		}
+		select {
+		case p.EncoderChan <- EncoderEvent{Encoder: i, Detents: detents, Delta: delta, Time: t}:
+		case <-p.DoneChan:
+			return
+		}
 	}
 
-	return data
+	var gestures []Gesture
+	for _, event := range p.Decoder.Feed(data, t) {
+		gestures = append(gestures, p.Recognizer.Feed(event)...)
+	}
+	gestures = append(gestures, p.Recognizer.Tick(t)...)
+	for _, gesture := range gestures {
+		select {
+		case p.GestureChan <- gesture:
+		case <-p.DoneChan:
+			return
+		}
+	}
 }
 
+// Start runs the piso on its own goroutine, it is added to Wait before
+// the goroutine starts so a Wait right after sees it
+func (p *PISO) Start() {
+	p.Wait.Add(1)
+	go p.Run()
+}
+
+// read at the configured rate and send gestures until DoneChan is
+// closed, the bus is closed on return. Callers add it to Wait, see Start
 func (p *PISO) Run() {
-	for {
-		interval_timer := time.NewTimer(p.interval * time.Second)
+	defer p.Wait.Done()
+	defer p.cancel()
 
+	interval_ticker := time.NewTicker(p.interval)
+	defer interval_ticker.Stop()
+	for {
 		select {
-		case <-interval_timer.C:
-			p.Wait.Add(1)
-			p.Read()
+		case t := <-interval_ticker.C:
+			p.poll(t)
 		case <-p.DoneChan:
-			interval_timer.Stop()
-			p.cancel()
+			return
 		}
 	}
 }
 
 func (p *PISO) cancel() {
-	p.Wait.Wait()
-	rpio.SpiEnd(p.SpiDev)
+	if err := p.Bus.Close(); err != nil {
+		p.ErrorLog.Println(err)
+	}
 }
@@ -0,0 +1,81 @@
+package multiplexer
+
+import (
+	"bytes"
+	"io"
+	"log"
+	"sync"
+	"testing"
+	"time"
+)
+
+func newTestPiso(t *testing.T, ndev int, reads_per_sec int, bus *FakeSPI) (*PISO, *FakePin) {
+	t.Helper()
+	pin := FakePin{}
+	piso, err := NewPiso(&pin, ndev, bus, reads_per_sec, &sync.WaitGroup{}, log.New(io.Discard, "", 0))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return piso, &pin
+}
+
+func Test_PisoRead(t *testing.T) {
+	frames := [][]byte{{0x00, 0x00}, {0x01, 0x80}, {0xff, 0x00}}
+	bus := NewFakeSPI(frames...)
+	piso, pin := newTestPiso(t, 2, 100, bus)
+
+	for i, want := range append(frames, frames[2]) {
+		got, err := piso.Read()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(got, want) {
+			t.Fatalf("read %d: expected %08b, got %08b", i, want, got)
+		}
+	}
+	// the initial high plus one load pulse per read
+	if pulses := pin.Pulses(); pulses != 5 {
+		t.Fatalf("expected 5 pulses, got %d", pulses)
+	}
+
+	bus.Push([]byte{0x01})
+	if _, err := piso.Read(); err == nil {
+		t.Fatal("short frame accepted")
+	}
+}
+
+func Test_PisoRun(t *testing.T) {
+	bus := NewFakeSPI([]byte{0x01})
+	piso, _ := newTestPiso(t, 1, 100, bus)
+
+	stopped := make(chan bool)
+	piso.Start()
+	go func() {
+		piso.Wait.Wait()
+		close(stopped)
+	}()
+	time.Sleep(200 * time.Millisecond)
+	close(piso.DoneChan)
+
+	select {
+	case <-stopped:
+	case <-time.After(time.Second):
+		t.Fatal("Run did not return")
+	}
+	if reads := bus.Reads(); reads < 10 || reads > 25 {
+		t.Fatalf("expected about 20 reads at 100/s, got %d", reads)
+	}
+	if _, err := bus.Receive(1); err != ErrClosed {
+		t.Fatalf("bus not closed: %v", err)
+	}
+}
+
+func Test_NewPiso(t *testing.T) {
+	logger := log.New(io.Discard, "", 0)
+	if _, err := NewPiso(&FakePin{}, 1, NewFakeSPI(), 0, &sync.WaitGroup{}, logger); err == nil {
+		t.Fatal("zero reads per second accepted")
+	}
+	if _, err := NewPiso(&FakePin{}, 0, NewFakeSPI(), 10, &sync.WaitGroup{}, logger); err == nil {
+		t.Fatal("zero chips accepted")
+	}
+}
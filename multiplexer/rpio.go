@@ -0,0 +1,99 @@
+package multiplexer
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/stianeikeland/go-rpio/v4"
+)
+
+const spiSpeed = 5000000 // 5 MHz, well below the 74HC165 and 74HC595 limits
+
+// go-rpio keeps the gpio memory and the spi setup in globals, the bus is
+// shared by all RpioSPI values and the chip select is set per transfer
+var (
+	rpioMu    sync.Mutex
+	rpioUsers int
+	spiUsers  = make(map[rpio.SpiDev]int)
+)
+
+// SPI backend using the bcm283x registers through go-rpio, needs
+// /dev/gpiomem (or root for /dev/mem)
+type RpioSPI struct {
+	dev rpio.SpiDev
+	cs  ChipSelect
+}
+
+func OpenRpioSPI(dev rpio.SpiDev, cs ChipSelect) (*RpioSPI, error) {
+	rpioMu.Lock()
+	defer rpioMu.Unlock()
+
+	if rpioUsers == 0 {
+		if err := rpio.Open(); err != nil {
+			return nil, fmt.Errorf("multiplexer: open gpio memory: %w", err)
+		}
+	}
+	if spiUsers[dev] == 0 {
+		if err := rpio.SpiBegin(dev); err != nil {
+			if rpioUsers == 0 {
+				rpio.Close()
+			}
+			return nil, fmt.Errorf("multiplexer: begin spi: %w", err)
+		}
+		rpio.SpiSpeed(spiSpeed)
+	}
+	rpioUsers++
+	spiUsers[dev]++
+	return &RpioSPI{dev: dev, cs: cs}, nil
+}
+
+func (s *RpioSPI) Receive(n int) ([]byte, error) {
+	rpioMu.Lock()
+	defer rpioMu.Unlock()
+	rpio.SpiChipSelect(uint8(s.cs))
+	return rpio.SpiReceive(n), nil
+}
+
+func (s *RpioSPI) Transmit(data []byte) error {
+	rpioMu.Lock()
+	defer rpioMu.Unlock()
+	rpio.SpiChipSelect(uint8(s.cs))
+	rpio.SpiTransmit(data...)
+	return nil
+}
+
+func (s *RpioSPI) Close() error {
+	rpioMu.Lock()
+	defer rpioMu.Unlock()
+
+	spiUsers[s.dev]--
+	if spiUsers[s.dev] == 0 {
+		rpio.SpiEnd(s.dev)
+	}
+	rpioUsers--
+	if rpioUsers == 0 {
+		return rpio.Close()
+	}
+	return nil
+}
+
+// output pin on the gpio header, the gpio memory has to be open, e.g.
+// through OpenRpioSPI
+type RpioPin struct {
+	pin rpio.Pin
+}
+
+func NewRpioPin(pin int) RpioPin {
+	p := RpioPin{pin: rpio.Pin(pin)}
+	p.pin.Output()
+	p.pin.High()
+	return p
+}
+
+func (p RpioPin) High() {
+	p.pin.High()
+}
+
+func (p RpioPin) Low() {
+	p.pin.Low()
+}
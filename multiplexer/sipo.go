@@ -0,0 +1,45 @@
+package multiplexer
+
+import (
+	"errors"
+	"fmt"
+)
+
+// SIPO drives chained 74HC595 shift registers, the outputs change when
+// the latch (RCLK) pin goes high
+type SIPO struct {
+	Latch Pin
+	NDev  int // number of chips
+	Bus   SPI
+}
+
+func NewSipo(latch Pin, ndev int, bus SPI) (*SIPO, error) {
+	if ndev < 1 {
+		return nil, errors.New("multiplexer: at least one chip is needed")
+	}
+	latch.Low()
+	return &SIPO{Latch: latch, NDev: ndev, Bus: bus}, nil
+}
+
+// Write sets the outputs, data[0] goes to the chip next to the pi. The
+// byte shifted out first ends up in the last chip of the chain, so the
+// data is sent in reverse
+func (s *SIPO) Write(data []byte) error {
+	if len(data) != s.NDev {
+		return fmt.Errorf("multiplexer: %d bytes for %d chips", len(data), s.NDev)
+	}
+	reversed := make([]byte, len(data))
+	for i := range data {
+		reversed[len(data)-1-i] = data[i]
+	}
+	if err := s.Bus.Transmit(reversed); err != nil {
+		return err
+	}
+	s.Latch.High()
+	s.Latch.Low()
+	return nil
+}
+
+func (s *SIPO) Close() error {
+	return s.Bus.Close()
+}
@@ -0,0 +1,29 @@
+package multiplexer
+
+import (
+	"reflect"
+	"testing"
+)
+
+func Test_SipoWrite(t *testing.T) {
+	bus := NewFakeSPI()
+	latch := FakePin{}
+	sipo, err := NewSipo(&latch, 2, bus)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := sipo.Write([]byte{0x01, 0x80}); err != nil {
+		t.Fatal(err)
+	}
+	// the byte for the last chip is shifted out first
+	if written := bus.Written(); !reflect.DeepEqual(written, [][]byte{{0x80, 0x01}}) {
+		t.Fatalf("unexpected frames %x", written)
+	}
+	if pulses := latch.Pulses(); pulses != 1 {
+		t.Fatalf("expected one latch pulse, got %d", pulses)
+	}
+	if err := sipo.Write([]byte{0x01}); err == nil {
+		t.Fatal("short frame accepted")
+	}
+}
@@ -0,0 +1,115 @@
+package multiplexer
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"volumgui/client"
+)
+
+const (
+	DefaultVolumeStep     = 2
+	DefaultVolumeInterval = 150 * time.Millisecond
+	volumeSettle          = time.Second // after this the client state is trusted again
+	volumeTimeout         = 5 * time.Second
+)
+
+// VolumeKnob turns encoder events into SetVolume calls, turns are
+// accumulated so at most one call is made per Interval
+type VolumeKnob struct {
+	Client   client.ClientInterface
+	State    func() client.State // current state of the client
+	Step     int                 // volume change per detent
+	Interval time.Duration
+	ErrChan  chan<- error // failed calls are sent here when set, dropped if nobody is ready
+	ErrorLog *log.Logger
+	target   int
+	pending  bool // target not sent yet
+	active   time.Time
+	sent     time.Time
+}
+
+func NewVolumeKnob(c client.ClientInterface, state func() client.State, error_log *log.Logger) *VolumeKnob {
+	return &VolumeKnob{
+		Client:   c,
+		State:    state,
+		Step:     DefaultVolumeStep,
+		Interval: DefaultVolumeInterval,
+		ErrorLog: error_log,
+	}
+}
+
+// add a turn, the client state lags behind while the knob is turned so
+// it is only used as base after the knob was left alone for a while
+func (v *VolumeKnob) add(delta int, t time.Time) {
+	if t.Sub(v.active) > volumeSettle {
+		v.target = v.State().Volume
+	}
+	v.active = t
+	v.target += delta * v.Step
+	if v.target < 0 {
+		v.target = 0
+	}
+	if v.target > 100 {
+		v.target = 100
+	}
+	v.pending = true
+}
+
+// send the target in the background, turns keep adding to the next
+// target while the player answers and the result arrives on result_chan
+func (v *VolumeKnob) flush(t time.Time, result_chan chan<- error) {
+	v.pending = false
+	v.sent = t
+	target := v.target
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), volumeTimeout)
+		defer cancel()
+		result_chan <- v.Client.SetVolume(ctx, target, false)
+	}()
+}
+
+// log a failed call and pass it on to ErrChan
+func (v *VolumeKnob) report(err error) {
+	v.ErrorLog.Println(err)
+	if v.ErrChan != nil {
+		select {
+		case v.ErrChan <- err:
+		default:
+		}
+	}
+}
+
+// Run handles the events of the knob until done_chan is closed, the first
+// turn is sent right away and the following ones once per Interval, with
+// one call in flight at a time
+func (v *VolumeKnob) Run(events <-chan EncoderEvent, done_chan <-chan bool) {
+	var flush_chan <-chan time.Time
+	sending := false
+	result_chan := make(chan error, 1)
+	schedule := func() {
+		if v.pending && !sending && flush_chan == nil {
+			flush_chan = time.After(time.Until(v.sent.Add(v.Interval)))
+		}
+	}
+	for {
+		select {
+		case event := <-events:
+			v.add(event.Delta, time.Now())
+			schedule()
+		case t := <-flush_chan:
+			flush_chan = nil
+			sending = true
+			v.flush(t, result_chan)
+		case err := <-result_chan:
+			sending = false
+			if err != nil {
+				v.report(err)
+			}
+			schedule()
+		case <-done_chan:
+			return
+		}
+	}
+}
@@ -0,0 +1,235 @@
+package presets
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"volumgui/client"
+)
+
+const (
+	DefaultSlots     = 9
+	playWaitInterval = 100 * time.Millisecond
+)
+
+var ErrEmptySlot = errors.New("empty preset")
+
+// playback saved to a preset slot
+type Preset struct {
+	Uri       string    `json:"uri"`
+	Service   string    `json:"service"`
+	Title     string    `json:"title"`
+	Artist    string    `json:"artist"`
+	Album     string    `json:"album"`
+	AlbumArt  string    `json:"albumart"`
+	TrackType string    `json:"trackType"`
+	Duration  int       `json:"duration"` // duration in seconds, 0 for streams
+	Position  int       `json:"position"` // position in milliseconds
+	Saved     time.Time `json:"saved"`
+}
+
+func (p Preset) QueueItem() client.QueueItem {
+	return client.QueueItem{
+		Uri:       p.Uri,
+		Service:   p.Service,
+		Name:      p.Title,
+		Artist:    p.Artist,
+		Album:     p.Album,
+		AlbumArt:  p.AlbumArt,
+		TrackType: p.TrackType,
+		Duration:  p.Duration,
+	}
+}
+
+// Presets keeps numbered slots in a json file and replays them through
+// the client
+type Presets struct {
+	mu       sync.Mutex
+	Path     string
+	Slots    int
+	slots    map[int]Preset
+	Client   client.ClientInterface
+	State    func() client.State // current state of the client
+	InfoLog  *log.Logger
+	ErrorLog *log.Logger
+}
+
+// NewPresets loads the presets stored at path, a missing file means no
+// presets were saved yet
+func NewPresets(path string, c client.ClientInterface, state func() client.State, info_log *log.Logger, error_log *log.Logger) (*Presets, error) {
+	p := Presets{
+		Path:     path,
+		Slots:    DefaultSlots,
+		slots:    make(map[int]Preset),
+		Client:   c,
+		State:    state,
+		InfoLog:  info_log,
+		ErrorLog: error_log,
+	}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return &p, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("presets: %w", err)
+	}
+	if err := json.Unmarshal(data, &p.slots); err != nil {
+		return nil, fmt.Errorf("presets: %s: %w", path, err)
+	}
+	return &p, nil
+}
+
+// DefaultPath is presets.json in the user config directory
+func DefaultPath() string {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "presets.json"
+	}
+	return filepath.Join(dir, "volumgui", "presets.json")
+}
+
+func (p *Presets) validateSlot(slot int) error {
+	if slot < 1 || slot > p.Slots {
+		return fmt.Errorf("presets: slot %d out of range [1, %d]", slot, p.Slots)
+	}
+	return nil
+}
+
+// Get returns the preset stored in slot
+func (p *Presets) Get(slot int) (Preset, error) {
+	if err := p.validateSlot(slot); err != nil {
+		return Preset{}, err
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	preset, ok := p.slots[slot]
+	if !ok {
+		return Preset{}, fmt.Errorf("presets: slot %d: %w", slot, ErrEmptySlot)
+	}
+	return preset, nil
+}
+
+// Slot returns the lowest slot holding uri, 0 if no slot does
+func (p *Presets) Slot(uri string) int {
+	if uri == "" {
+		return 0
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	for slot := 1; slot <= p.Slots; slot++ {
+		if preset, ok := p.slots[slot]; ok && preset.Uri == uri {
+			return slot
+		}
+	}
+	return 0
+}
+
+// Save stores the current playback in slot and writes all slots to disk
+func (p *Presets) Save(slot int) (Preset, error) {
+	if err := p.validateSlot(slot); err != nil {
+		return Preset{}, err
+	}
+	state := p.State()
+	if state.Uri == "" {
+		return Preset{}, fmt.Errorf("presets: slot %d: nothing playing", slot)
+	}
+	preset := Preset{
+		Uri:       state.Uri,
+		Service:   state.Service,
+		Title:     state.Title,
+		Artist:    state.Artist,
+		Album:     state.Album,
+		AlbumArt:  state.AlbumArt,
+		TrackType: state.TrackType,
+		Duration:  state.Duration,
+		Position:  state.Seek,
+		Saved:     time.Now(),
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	previous, existed := p.slots[slot]
+	p.slots[slot] = preset
+	if err := p.write(); err != nil {
+		if existed {
+			p.slots[slot] = previous
+		} else {
+			delete(p.slots, slot)
+		}
+		return Preset{}, err
+	}
+	p.InfoLog.Printf("preset %d saved: %s", slot, preset.Uri)
+	return preset, nil
+}
+
+// write to a temporary file first so a crash never leaves a truncated file
+func (p *Presets) write() error {
+	data, err := json.MarshalIndent(p.slots, "", "  ")
+	if err != nil {
+		return fmt.Errorf("presets: %w", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(p.Path), 0755); err != nil {
+		return fmt.Errorf("presets: %w", err)
+	}
+	tmp := p.Path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return fmt.Errorf("presets: %w", err)
+	}
+	if err := os.Rename(tmp, p.Path); err != nil {
+		return fmt.Errorf("presets: %w", err)
+	}
+	return nil
+}
+
+// Recall replaces the queue with the preset and seeks to the saved
+// position once it plays, streams start from the live position
+func (p *Presets) Recall(ctx context.Context, slot int) (Preset, error) {
+	preset, err := p.Get(slot)
+	if err != nil {
+		return Preset{}, err
+	}
+	queue, ok := p.Client.(client.QueueInterface)
+	if !ok {
+		return Preset{}, fmt.Errorf("presets: %w", client.ErrUnsupported)
+	}
+	if err := queue.ReplaceAndPlay(ctx, preset.QueueItem()); err != nil {
+		return Preset{}, err
+	}
+	if preset.Duration == 0 || preset.Position <= 0 {
+		return preset, nil
+	}
+	if err := p.waitPlaying(ctx, preset.Uri); err != nil {
+		return Preset{}, fmt.Errorf("presets: slot %d: %w", slot, err)
+	}
+	position := time.Duration(preset.Position) * time.Millisecond
+	if err := p.Client.Seek(ctx, position); err != nil {
+		return Preset{}, err
+	}
+	p.InfoLog.Printf("preset %d recalled: %s", slot, preset.Uri)
+	return preset, nil
+}
+
+// seeking before the player switched to the new item would seek the old one
+func (p *Presets) waitPlaying(ctx context.Context, uri string) error {
+	ticker := time.NewTicker(playWaitInterval)
+	defer ticker.Stop()
+	for {
+		if state := p.State(); state.Uri == uri && state.Status == "play" {
+			return nil
+		}
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
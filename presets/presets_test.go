@@ -0,0 +1,99 @@
+package presets
+
+import (
+	"context"
+	"io"
+	"log"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"volumgui/client"
+)
+
+// client recording replaceAndPlay and seek, a replaced item starts playing
+type fakeClient struct {
+	client.ClientInterface
+	client.QueueInterface
+	mu     sync.Mutex
+	state  client.State
+	played []client.QueueItem
+	seeks  []time.Duration
+}
+
+func (f *fakeClient) ReplaceAndPlay(ctx context.Context, item client.QueueItem) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.played = append(f.played, item)
+	f.state = client.State{Uri: item.Uri, Status: "play", Duration: item.Duration}
+	return nil
+}
+
+func (f *fakeClient) Seek(ctx context.Context, position time.Duration) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.seeks = append(f.seeks, position)
+	return nil
+}
+
+func (f *fakeClient) currentState() client.State {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.state
+}
+
+func newTestPresets(t *testing.T, path string, c *fakeClient) *Presets {
+	t.Helper()
+	logger := log.New(io.Discard, "", 0)
+	p, err := NewPresets(path, c, c.currentState, logger, logger)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return p
+}
+
+func Test_PresetsSaveRecall(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "volumgui", "presets.json")
+	c := fakeClient{state: client.State{
+		Uri: "mnt/USB/cream/01.flac", Service: "mpd", Title: "I Feel Free",
+		Status: "play", Seek: 42000, Duration: 175,
+	}}
+	p := newTestPresets(t, path, &c)
+
+	if _, err := p.Save(3); err != nil {
+		t.Fatal(err)
+	}
+
+	// presets survive a restart
+	c.state = client.State{Uri: "webradio/other", Status: "play"}
+	p = newTestPresets(t, path, &c)
+	if slot := p.Slot("mnt/USB/cream/01.flac"); slot != 3 {
+		t.Fatalf("expected slot 3, got %d", slot)
+	}
+	preset, err := p.Recall(context.Background(), 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if preset.Title != "I Feel Free" || len(c.played) != 1 || c.played[0].Uri != "mnt/USB/cream/01.flac" {
+		t.Fatalf("unexpected recall %+v, played %+v", preset, c.played)
+	}
+	if len(c.seeks) != 1 || c.seeks[0] != 42*time.Second {
+		t.Fatalf("unexpected seeks %v", c.seeks)
+	}
+}
+
+func Test_PresetsStream(t *testing.T) {
+	c := fakeClient{state: client.State{Uri: "http://radio.example/stream", Service: "webradio", Status: "play", Seek: 90000}}
+	p := newTestPresets(t, filepath.Join(t.TempDir(), "presets.json"), &c)
+
+	if _, err := p.Save(1); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := p.Recall(context.Background(), 1); err != nil {
+		t.Fatal(err)
+	}
+	if len(c.seeks) != 0 {
+		t.Fatalf("stream was seeked: %v", c.seeks)
+	}
+}
@@ -0,0 +1,162 @@
+package ui
+
+import (
+	"context"
+
+	"volumgui/client"
+
+	ui "github.com/gizak/termui/v3"
+	"github.com/gizak/termui/v3/widgets"
+)
+
+const noLibraryStatus = "library: not supported by this backend"
+
+// library browser with a tab per browse source
+type browserView struct {
+	d       *Display
+	tabs    *widgets.TabPane
+	list    *widgets.List
+	sources []client.BrowseSource
+	page    itemList
+	history []string // uris browsed from the root of the source, the last one is shown
+}
+
+func newBrowserView(d *Display) *browserView {
+	tabs := widgets.NewTabPane()
+	return &browserView{d: d, tabs: tabs, list: newSelectList("library")}
+}
+
+func (v *browserView) name() string {
+	return "library"
+}
+
+func (v *browserView) rows() []interface{} {
+	return []interface{}{
+		ui.NewRow(0.5/5, v.tabs),
+		ui.NewRow(2.5/5, v.list),
+	}
+}
+
+func (v *browserView) drawables() []ui.Drawable {
+	return []ui.Drawable{v.tabs, v.list}
+}
+
+// sources are loaded once, the page is reloaded on every visit
+func (v *browserView) open() {
+	if v.sources == nil {
+		v.loadSources()
+	}
+	if len(v.history) > 0 {
+		v.browse(v.history[len(v.history)-1], false)
+	}
+}
+
+// browse uri with a fresh history, used to jump into the library from elsewhere
+func (v *browserView) browseFrom(uri string) {
+	v.history = nil
+	v.browse(uri, true)
+}
+
+func (v *browserView) handle(e ui.Event) bool {
+	if scrollList(v.list, e.ID) {
+		v.d.render(v.list)
+		return true
+	}
+	item := v.page.selected(v.list.SelectedRow)
+	switch e.ID {
+	case "h":
+		v.selectSource(v.tabs.ActiveTabIndex - 1)
+	case "l":
+		v.selectSource(v.tabs.ActiveTabIndex + 1)
+	case "<Backspace>", "<C-<Backspace>>":
+		v.back()
+	case "<Enter>":
+		if item == nil {
+			return true
+		}
+		if item.Playable() {
+			v.d.playItem(item, playAdd)
+		} else {
+			v.browse(item.Uri, true)
+		}
+	case "p":
+		v.d.playItem(item, playAdd)
+	case "a":
+		v.d.playItem(item, playEnqueue)
+	case "R":
+		v.d.playItem(item, playReplace)
+	default:
+		return false
+	}
+	return true
+}
+
+func (v *browserView) loadSources() {
+	lib, ok := v.d.Client.(client.LibraryInterface)
+	if !ok {
+		v.d.setStatus(noLibraryStatus)
+		return
+	}
+	v.d.fetch(func(ctx context.Context) (func(), error) {
+		sources, err := lib.GetBrowseSources(ctx)
+		if err != nil {
+			return nil, err
+		}
+		return func() {
+			v.sources = sources
+			v.tabs.TabNames = make([]string, len(sources))
+			for i := range sources {
+				v.tabs.TabNames[i] = sources[i].Name
+			}
+			v.d.render(v.tabs)
+			if len(v.history) == 0 {
+				v.selectSource(0)
+			}
+		}, nil
+	})
+}
+
+// show the root of the source at index
+func (v *browserView) selectSource(index int) {
+	if index < 0 || index >= len(v.sources) {
+		return
+	}
+	v.tabs.ActiveTabIndex = index
+	v.history = nil
+	v.d.render(v.tabs)
+	v.browse(v.sources[index].Uri, true)
+}
+
+func (v *browserView) back() {
+	if len(v.history) < 2 {
+		return
+	}
+	v.history = v.history[:len(v.history)-1]
+	v.browse(v.history[len(v.history)-1], false)
+}
+
+// load the page at uri, push adds it to the history once loaded
+func (v *browserView) browse(uri string, push bool) {
+	lib, ok := v.d.Client.(client.LibraryInterface)
+	if !ok {
+		v.d.setStatus(noLibraryStatus)
+		return
+	}
+	v.d.fetch(func(ctx context.Context) (func(), error) {
+		result, err := lib.BrowseLibrary(ctx, uri)
+		if err != nil {
+			return nil, err
+		}
+		return func() {
+			v.page = newItemList(result.Navigation.Lists)
+			if push {
+				v.history = append(v.history, uri)
+				v.list.SelectedRow = v.page.first()
+			}
+			v.list.Title = uri
+			v.list.Rows = v.page.rows
+			clampSelection(v.list)
+			v.d.render(v.list)
+		}, nil
+	})
+}
@@ -0,0 +1,138 @@
+package ui
+
+import (
+	"context"
+	"fmt"
+
+	"volumgui/client"
+
+	"github.com/gizak/termui/v3/widgets"
+)
+
+// lists are styled by the theme
+func newSelectList(title string) *widgets.List {
+	list := widgets.NewList()
+	list.Title = title
+	list.WrapText = false
+	return list
+}
+
+// move the selection of a list, returns false for keys that don't scroll
+func scrollList(list *widgets.List, key string) bool {
+	switch key {
+	case "<Down>", "j":
+		list.ScrollDown()
+	case "<Up>", "k":
+		list.ScrollUp()
+	case "<PageDown>", "<C-d>":
+		list.ScrollHalfPageDown()
+	case "<PageUp>", "<C-u>":
+		list.ScrollHalfPageUp()
+	case "<Home>":
+		list.ScrollTop()
+	case "<End>":
+		list.ScrollBottom()
+	default:
+		return false
+	}
+	return true
+}
+
+// keep the selection within the rows after they changed
+func clampSelection(list *widgets.List) {
+	if list.SelectedRow >= len(list.Rows) {
+		list.SelectedRow = len(list.Rows) - 1
+	}
+	if list.SelectedRow < 0 {
+		list.SelectedRow = 0
+	}
+}
+
+// several titled lists flattened into the rows of one widget list,
+// rows holding a section title have no item
+type itemList struct {
+	rows  []string
+	items []*client.BrowseItem
+}
+
+func newItemList(lists []client.BrowseList) itemList {
+	var l itemList
+	for i := range lists {
+		if lists[i].Title != "" && len(lists) > 1 {
+			l.rows = append(l.rows, fmt.Sprintf("[%s](mod:bold)", lists[i].Title))
+			l.items = append(l.items, nil)
+		}
+		for j := range lists[i].Items {
+			item := &lists[i].Items[j]
+			l.rows = append(l.rows, getItemRow(item))
+			l.items = append(l.items, item)
+		}
+	}
+	return l
+}
+
+// item at row, nil for section titles
+func (l itemList) selected(row int) *client.BrowseItem {
+	if row < 0 || row >= len(l.items) {
+		return nil
+	}
+	return l.items[row]
+}
+
+// first row holding an item
+func (l itemList) first() int {
+	for i := range l.items {
+		if l.items[i] != nil {
+			return i
+		}
+	}
+	return 0
+}
+
+func getItemRow(item *client.BrowseItem) string {
+	marker := "+ "
+	if item.Playable() {
+		marker = "  "
+	}
+	if item.Artist != "" {
+		return fmt.Sprintf("%s%s - %s", marker, item.Title, item.Artist)
+	}
+	return marker + item.Title
+}
+
+// ways of playing an item from a list
+type playMode int
+
+const (
+	playAdd     playMode = iota // add to the queue and play
+	playEnqueue                 // add to the end of the queue
+	playReplace                 // replace the queue and play
+)
+
+// play or enqueue a library item, shared by the views listing items
+func (d *Display) playItem(item *client.BrowseItem, mode playMode) {
+	if item == nil {
+		return
+	}
+	q, ok := d.Client.(client.QueueInterface)
+	if !ok {
+		d.setStatus(noQueueStatus)
+		return
+	}
+	queue_item := item.QueueItem()
+	d.fetch(func(ctx context.Context) (func(), error) {
+		var err error
+		switch mode {
+		case playAdd:
+			err = q.AddPlay(ctx, queue_item)
+		case playEnqueue:
+			err = q.AddToQueue(ctx, queue_item)
+		case playReplace:
+			err = q.ReplaceAndPlay(ctx, queue_item)
+		}
+		if err != nil {
+			return nil, err
+		}
+		return func() { d.setStatus("added " + queue_item.Name) }, nil
+	})
+}
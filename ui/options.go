@@ -0,0 +1,70 @@
+package ui
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	ui "github.com/gizak/termui/v3"
+)
+
+// Options are the display settings of the config file
+type Options struct {
+	Marquee       time.Duration    `yaml:"marquee"`        // step interval of the scrolling title
+	WifiInterface string           `yaml:"wifi_interface"` // wireless interface of the signal in the footer, empty to hide it
+	Theme         string           `yaml:"theme"`          // built in or user theme
+	Themes        map[string]Theme `yaml:"themes"`         // user themes by name
+}
+
+func DefaultOptions() Options {
+	return Options{
+		Marquee:       500 * time.Millisecond,
+		WifiInterface: "wlp5s0",
+		Theme:         DefaultTheme,
+	}
+}
+
+var interfaceName = regexp.MustCompile(`^[A-Za-z0-9_.-]{0,15}$`)
+
+var colorNames = map[string]ui.Color{
+	"default": ui.ColorClear,
+	"black":   ui.ColorBlack,
+	"red":     ui.ColorRed,
+	"green":   ui.ColorGreen,
+	"yellow":  ui.ColorYellow,
+	"blue":    ui.ColorBlue,
+	"magenta": ui.ColorMagenta,
+	"cyan":    ui.ColorCyan,
+	"white":   ui.ColorWhite,
+}
+
+// ParseColor parses a color name or a number of the 256 color palette
+func ParseColor(name string) (ui.Color, error) {
+	name = strings.ToLower(strings.TrimSpace(name))
+	if color, ok := colorNames[name]; ok {
+		return color, nil
+	}
+	n, err := strconv.Atoi(name)
+	if err != nil || n < 0 || n > 255 {
+		return ui.ColorClear, fmt.Errorf("unknown color %q, expected a name like magenta or 0-255", name)
+	}
+	return ui.Color(n), nil
+}
+
+// CheckInterface checks the name of the wifi interface, it ends up in a
+// shell command
+func CheckInterface(name string) error {
+	if !interfaceName.MatchString(name) {
+		return fmt.Errorf("invalid interface name %q", name)
+	}
+	return nil
+}
+
+// color parses a validated color, invalid ones fall back to the
+// terminal default
+func color(name string) ui.Color {
+	c, _ := ParseColor(name)
+	return c
+}
@@ -0,0 +1,159 @@
+package ui
+
+import (
+	"context"
+	"fmt"
+
+	"volumgui/client"
+
+	ui "github.com/gizak/termui/v3"
+	"github.com/gizak/termui/v3/widgets"
+)
+
+const noPlaylistStatus = "playlists: not supported by this backend"
+
+// stored playlists, the selected one is played or extended with the
+// current item
+type playlistView struct {
+	d         *Display
+	list      *widgets.List
+	playlists []client.Playlist
+}
+
+func newPlaylistView(d *Display) *playlistView {
+	return &playlistView{d: d, list: newSelectList("playlists")}
+}
+
+func (v *playlistView) name() string {
+	return "playlists"
+}
+
+func (v *playlistView) rows() []interface{} {
+	return []interface{}{ui.NewRow(3.0/5, v.list)}
+}
+
+func (v *playlistView) drawables() []ui.Drawable {
+	return []ui.Drawable{v.list}
+}
+
+func (v *playlistView) open() {
+	v.refresh()
+}
+
+func (v *playlistView) handle(e ui.Event) bool {
+	if scrollList(v.list, e.ID) {
+		v.d.render(v.list)
+		return true
+	}
+	switch e.ID {
+	case "<Enter>":
+		v.apply(func(ctx context.Context, p client.PlaylistInterface, name string) error {
+			return p.PlayPlaylist(ctx, name)
+		})
+		return true
+	case "a":
+		item := v.d.currentItem()
+		v.apply(func(ctx context.Context, p client.PlaylistInterface, name string) error {
+			return p.AddToPlaylist(ctx, name, item)
+		})
+		return true
+	case "x":
+		item := v.d.currentItem()
+		v.apply(func(ctx context.Context, p client.PlaylistInterface, name string) error {
+			return p.RemoveFromPlaylist(ctx, name, item)
+		})
+		return true
+	case "n":
+		v.d.openPrompt("new playlist:", v.create)
+		return true
+	case "D":
+		if name, ok := v.selected(); ok {
+			v.d.openPrompt(fmt.Sprintf("delete %s? (y/n):", name), func(answer string) {
+				if answer == "y" {
+					v.apply(func(ctx context.Context, p client.PlaylistInterface, name string) error {
+						return p.DeletePlaylist(ctx, name)
+					})
+				}
+			})
+		}
+		return true
+	case "F":
+		if p, ok := v.d.Client.(client.PlaylistInterface); ok {
+			v.d.do(p.PlayFavourites)
+		} else {
+			v.d.setStatus(noPlaylistStatus)
+		}
+		return true
+	}
+	return false
+}
+
+func (v *playlistView) selected() (string, bool) {
+	if v.list.SelectedRow >= len(v.playlists) {
+		return "", false
+	}
+	return v.playlists[v.list.SelectedRow].Name, true
+}
+
+func (v *playlistView) create(name string) {
+	p, ok := v.d.Client.(client.PlaylistInterface)
+	if !ok {
+		v.d.setStatus(noPlaylistStatus)
+		return
+	}
+	v.d.fetch(func(ctx context.Context) (func(), error) {
+		if err := p.CreatePlaylist(ctx, name); err != nil {
+			return nil, err
+		}
+		return v.reload(ctx, p)
+	})
+}
+
+// run a playlist operation on the selected playlist and reload the list
+func (v *playlistView) apply(op func(context.Context, client.PlaylistInterface, string) error) {
+	p, ok := v.d.Client.(client.PlaylistInterface)
+	if !ok {
+		v.d.setStatus(noPlaylistStatus)
+		return
+	}
+	name, ok := v.selected()
+	if !ok {
+		return
+	}
+	v.d.fetch(func(ctx context.Context) (func(), error) {
+		if err := op(ctx, p, name); err != nil {
+			return nil, err
+		}
+		return v.reload(ctx, p)
+	})
+}
+
+func (v *playlistView) refresh() {
+	p, ok := v.d.Client.(client.PlaylistInterface)
+	if !ok {
+		v.d.setStatus(noPlaylistStatus)
+		return
+	}
+	v.d.fetch(func(ctx context.Context) (func(), error) {
+		return v.reload(ctx, p)
+	})
+}
+
+func (v *playlistView) reload(ctx context.Context, p client.PlaylistInterface) (func(), error) {
+	playlists, err := p.ListPlaylists(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return func() { v.update(playlists) }, nil
+}
+
+func (v *playlistView) update(playlists []client.Playlist) {
+	v.playlists = playlists
+	v.list.Rows = make([]string, len(playlists))
+	for i, playlist := range playlists {
+		v.list.Rows[i] = playlist.Name
+	}
+	clampSelection(v.list)
+	v.list.Title = fmt.Sprintf("playlists (%d)", len(v.playlists))
+	v.d.render(v.list)
+}
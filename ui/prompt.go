@@ -0,0 +1,57 @@
+package ui
+
+import (
+	"fmt"
+
+	ui "github.com/gizak/termui/v3"
+)
+
+// single line input shown in the footer while active
+type prompt struct {
+	label    string             // text in front of the input
+	input    []rune             // current input
+	onSubmit func(input string) // called with the input on <Enter>
+	onChange func(input string) // called after every edit, optional
+	onCancel func()             // called when dismissed without submitting, optional
+}
+
+func newPrompt(label string, onSubmit func(string)) *prompt {
+	return &prompt{
+		label:    label,
+		onSubmit: onSubmit,
+	}
+}
+
+// handle a key event, returns false once the prompt is finished
+func (p *prompt) handle(e ui.Event) bool {
+	length := len(p.input)
+	switch e.ID {
+	case "<Enter>":
+		p.onSubmit(string(p.input))
+		return false
+	case "<Escape>", "<C-c>":
+		if p.onCancel != nil {
+			p.onCancel()
+		}
+		return false
+	case "<Backspace>", "<C-<Backspace>>":
+		if len(p.input) > 0 {
+			p.input = p.input[:len(p.input)-1]
+		}
+	case "<Space>":
+		p.input = append(p.input, ' ')
+	default:
+		// printable keys are reported as single characters
+		if r := []rune(e.ID); len(r) == 1 {
+			p.input = append(p.input, r[0])
+		}
+	}
+	if p.onChange != nil && len(p.input) != length {
+		p.onChange(string(p.input))
+	}
+	return true
+}
+
+func (p *prompt) String() string {
+	return fmt.Sprintf("%s %s_", p.label, string(p.input))
+}
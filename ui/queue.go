@@ -0,0 +1,159 @@
+package ui
+
+import (
+	"context"
+	"fmt"
+
+	"volumgui/client"
+
+	ui "github.com/gizak/termui/v3"
+	"github.com/gizak/termui/v3/widgets"
+)
+
+const noQueueStatus = "queue: not supported by this backend"
+
+// scrollable play queue, the current item is marked
+type queueView struct {
+	d     *Display
+	list  *widgets.List
+	queue client.Queue
+}
+
+func newQueueView(d *Display) *queueView {
+	return &queueView{d: d, list: newSelectList("queue")}
+}
+
+func (v *queueView) name() string {
+	return "queue"
+}
+
+func (v *queueView) rows() []interface{} {
+	return []interface{}{ui.NewRow(3.0/5, v.list)}
+}
+
+func (v *queueView) drawables() []ui.Drawable {
+	return []ui.Drawable{v.list}
+}
+
+func (v *queueView) open() {
+	v.refresh()
+}
+
+func (v *queueView) handle(e ui.Event) bool {
+	if scrollList(v.list, e.ID) {
+		v.d.render(v.list)
+		return true
+	}
+	switch e.ID {
+	case "<Enter>":
+		v.apply(func(ctx context.Context, q client.QueueInterface, index int) error {
+			return q.PlayIndex(ctx, index)
+		})
+		return true
+	case "d", "<Delete>":
+		v.apply(func(ctx context.Context, q client.QueueInterface, index int) error {
+			return q.RemoveFromQueue(ctx, index)
+		})
+		return true
+	case "J":
+		v.move(1)
+		return true
+	case "K":
+		v.move(-1)
+		return true
+	case "C":
+		if len(v.queue) > 0 {
+			v.d.openPrompt(fmt.Sprintf("clear %d items from the queue? (y/n):", len(v.queue)), func(answer string) {
+				if answer == "y" {
+					v.apply(func(ctx context.Context, q client.QueueInterface, index int) error {
+						return q.ClearQueue(ctx)
+					})
+				}
+			})
+		}
+		return true
+	}
+	return false
+}
+
+// move the selected item by offset and keep it selected
+func (v *queueView) move(offset int) {
+	to := v.list.SelectedRow + offset
+	if to < 0 || to >= len(v.queue) {
+		return
+	}
+	v.apply(func(ctx context.Context, q client.QueueInterface, index int) error {
+		return q.MoveQueue(ctx, index, to)
+	})
+	v.list.SelectedRow = to
+}
+
+// run a queue operation on the selected index and reload the queue
+func (v *queueView) apply(op func(context.Context, client.QueueInterface, int) error) {
+	q, ok := v.d.Client.(client.QueueInterface)
+	if !ok {
+		v.d.setStatus(noQueueStatus)
+		return
+	}
+	if len(v.queue) == 0 {
+		return
+	}
+	index := v.list.SelectedRow
+	v.d.fetch(func(ctx context.Context) (func(), error) {
+		if err := op(ctx, q, index); err != nil {
+			return nil, err
+		}
+		queue, err := q.GetQueue(ctx)
+		if err != nil {
+			return nil, err
+		}
+		return func() { v.update(queue) }, nil
+	})
+}
+
+func (v *queueView) refresh() {
+	q, ok := v.d.Client.(client.QueueInterface)
+	if !ok {
+		v.d.setStatus(noQueueStatus)
+		return
+	}
+	v.d.fetch(func(ctx context.Context) (func(), error) {
+		queue, err := q.GetQueue(ctx)
+		if err != nil {
+			return nil, err
+		}
+		return func() { v.update(queue) }, nil
+	})
+}
+
+func (v *queueView) update(queue client.Queue) {
+	v.queue = queue
+	v.list.Rows = v.getQueueRows()
+	clampSelection(v.list)
+	v.list.Title = fmt.Sprintf("queue (%d)", len(v.queue))
+	v.d.render(v.list)
+}
+
+func (v *queueView) getQueueRows() []string {
+	rows := make([]string, len(v.queue))
+	for i, item := range v.queue {
+		marker := "  "
+		if i == v.d.State.Position && v.d.State.Status != "stop" {
+			marker = "> "
+		}
+		duration := ""
+		if item.Duration > 0 {
+			duration = " (" + PlayDuration{Duration: client.State{Duration: item.Duration}.Length()}.String() + ")"
+		}
+		rows[i] = fmt.Sprintf("%s%3d  %s - %s%s", marker, i+1, item.Name, item.Artist, duration)
+	}
+	return rows
+}
+
+// the current item changed, reload to move the marker and pick up
+// items added from elsewhere
+func (v *queueView) stateChanged(previous client.State) {
+	if previous.Position != v.d.State.Position || previous.Status != v.d.State.Status {
+		v.refresh()
+	}
+}
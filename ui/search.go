@@ -0,0 +1,161 @@
+package ui
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"volumgui/client"
+
+	ui "github.com/gizak/termui/v3"
+	"github.com/gizak/termui/v3/widgets"
+)
+
+const (
+	searchDelay     = 300 * time.Millisecond // typing pause before an incremental search
+	searchMinLength = 2                      // shorter queries are not searched while typing
+)
+
+// search prompt and grouped results
+type searchView struct {
+	d       *Display
+	list    *widgets.List
+	query   string      // query of the shown results
+	pending string      // query of the latest search, older results are dropped
+	results itemList    // results of the latest search
+	timer   *time.Timer // pending incremental search
+}
+
+func newSearchView(d *Display) *searchView {
+	return &searchView{d: d, list: newSelectList("search")}
+}
+
+func (v *searchView) name() string {
+	return "search"
+}
+
+func (v *searchView) rows() []interface{} {
+	return []interface{}{ui.NewRow(3.0/5, v.list)}
+}
+
+func (v *searchView) drawables() []ui.Drawable {
+	return []ui.Drawable{v.list}
+}
+
+func (v *searchView) open() {}
+
+func (v *searchView) handle(e ui.Event) bool {
+	if scrollList(v.list, e.ID) {
+		v.d.render(v.list)
+		return true
+	}
+	item := v.results.selected(v.list.SelectedRow)
+	switch e.ID {
+	case "/":
+		v.prompt()
+	case "<Enter>":
+		if item == nil {
+			return true
+		}
+		if item.Playable() {
+			v.d.playItem(item, playAdd)
+		} else {
+			// artists and albums are browsed in the library
+			v.d.setView(v.d.browser)
+			v.d.browser.browseFrom(item.Uri)
+		}
+	case "p":
+		v.d.playItem(item, playAdd)
+	case "a":
+		v.d.playItem(item, playEnqueue)
+	case "R":
+		v.d.playItem(item, playReplace)
+	default:
+		return false
+	}
+	return true
+}
+
+// open the search prompt, results update while typing
+func (v *searchView) prompt() {
+	v.d.openPrompt("search:", func(query string) {
+		v.cancel()
+		v.search(query)
+	})
+	v.d.prompt.input = []rune(v.query)
+	v.d.prompt.onChange = v.schedule
+	v.d.prompt.onCancel = v.cancel
+	v.d.uiFooterLeft.Text = v.d.getFooterString()
+	v.d.render(v.d.uiFooterLeft)
+}
+
+// search once typing pauses
+func (v *searchView) schedule(query string) {
+	v.cancel()
+	if len([]rune(strings.TrimSpace(query))) < searchMinLength {
+		return
+	}
+	v.timer = time.AfterFunc(searchDelay, func() {
+		select {
+		case v.d.applyChan <- func() { v.search(query) }:
+		case <-v.d.DoneChan:
+		}
+	})
+}
+
+func (v *searchView) cancel() {
+	if v.timer != nil {
+		v.timer.Stop()
+		v.timer = nil
+	}
+}
+
+func (v *searchView) search(query string) {
+	query = strings.TrimSpace(query)
+	if query == "" || query == v.pending {
+		return
+	}
+	lib, ok := v.d.Client.(client.LibraryInterface)
+	if !ok {
+		v.d.setStatus(noLibraryStatus)
+		return
+	}
+	v.pending = query
+	v.list.Title = fmt.Sprintf("search: %s ...", query)
+	v.d.render(v.list)
+	v.d.fetch(func(ctx context.Context) (func(), error) {
+		result, err := lib.Search(ctx, query)
+		if err != nil {
+			v.d.report(err)
+			return func() {
+				// a failed query can be searched again
+				if query == v.pending {
+					v.pending = ""
+					v.list.Title = v.title()
+					v.d.render(v.list)
+				}
+				v.d.setStatus(err.Error())
+			}, nil
+		}
+		return func() {
+			if query != v.pending {
+				return
+			}
+			v.query = query
+			v.results = newItemList(result.Lists())
+			v.list.Title = v.title()
+			v.list.Rows = v.results.rows
+			v.list.SelectedRow = v.results.first()
+			v.d.render(v.list)
+		}, nil
+	})
+}
+
+// title of the shown results
+func (v *searchView) title() string {
+	if v.query == "" {
+		return "search"
+	}
+	return fmt.Sprintf("search: %s (%d)", v.query, len(v.results.rows))
+}
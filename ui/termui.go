@@ -1,6 +1,7 @@
 package ui
 
 import (
+	"context"
 	"fmt"
 	"log"
 	"sync"
@@ -10,17 +11,24 @@ import (
 	"strings"
 	"time"
 
+	"volumgui/actions"
 	"volumgui/client"
 
 	ui "github.com/gizak/termui/v3"
 	"github.com/gizak/termui/v3/widgets"
 )
 
+const (
+	commandTimeout  = 10 * time.Second // upper bound for a client command issued by the ui
+	statusTimeout   = 5 * time.Second  // time a message stays in the status line
+	networkInterval = 10 * time.Second // time between reads of the ip and wifi signal
+	noPresetsStatus = "presets: not available"
+	noRunnerStatus  = "actions: not available"
+)
+
 var (
-	once       sync.Once
-	instance   *Display
-	termWidth  int
-	termHeight int
+	once     sync.Once
+	instance *Display
 )
 
 type Display struct {
@@ -31,7 +39,32 @@ type Display struct {
 	UiDoneChan        chan<- bool
 	StateChan         <-chan client.State
 	State             client.State
+	seeked            bool                    // the gauge shows a seek target until the next tick
+	ConnChan          <-chan client.ConnState // nil for clients without a connection
+	connState         client.ConnState
+	Client            client.ClientInterface
+	Runner            *actions.Runner           // runs the client actions of the key bindings
+	ErrChan           chan<- error              // failed commands are sent here when set, dropped if nobody is ready
+	Keys              map[string]actions.Action // key bindings, see bindings.DefaultKeys
+	Options           Options
+	theme             string // name of the theme applied
 	uiEventsChan      <-chan ui.Event
+	applyChan         chan func() // results of background work, applied on the ui goroutine
+	grid              *ui.Grid
+	width             int // terminal size, updated on resize
+	height            int
+	size              layoutSize // layout chosen by the terminal size
+	view              view       // view shown between header and footer
+	views             []view     // views selectable with the number keys
+	nowPlaying        *nowPlayingView
+	queue             *queueView
+	browser           *browserView
+	search            *searchView
+	playlists         *playlistView
+	prompt            *prompt
+	network           string    // ip, interface and wifi signal, read every networkInterval
+	status            string    // message shown in the footer
+	statusTime        time.Time // time the status was set
 	stringRotate      *stringRotate
 	uiHeader          *widgets.Paragraph
 	uiFooterLeft      *widgets.Paragraph
@@ -41,17 +74,13 @@ type Display struct {
 	uiPlaybackGuage   *widgets.Gauge
 }
 
-func NewUi(wg *sync.WaitGroup, doneChan <-chan bool, stateChan chan client.State, uiDoneChan chan<- bool, infoLog *log.Logger, errorLog *log.Logger) *Display {
+func NewUi(wg *sync.WaitGroup, doneChan <-chan bool, stateChan <-chan client.State, connChan <-chan client.ConnState, c client.ClientInterface, r *actions.Runner, keys map[string]actions.Action, options Options, uiDoneChan chan<- bool, infoLog *log.Logger, errorLog *log.Logger) *Display {
 	once.Do(func() {
 		if err := ui.Init(); err != nil {
 			errorLog.Fatalf("failed to initialize termui: %v", err)
 		}
 
-		show_border := false
-
 		grid := ui.NewGrid()
-		termWidth, termHeight = ui.TerminalDimensions()
-		grid.SetRect(0, 0, termWidth, termHeight)
 
 		display := Display{
 			Wait:         wg,
@@ -59,14 +88,21 @@ func NewUi(wg *sync.WaitGroup, doneChan <-chan bool, stateChan chan client.State
 			InfoLog:      infoLog,
 			ErrorLog:     errorLog,
 			StateChan:    stateChan,
+			ConnChan:     connChan,
+			Client:       c,
+			Runner:       r,
+			Keys:         keys,
+			Options:      options,
 			uiEventsChan: ui.PollEvents(),
+			applyChan:    make(chan func()),
+			grid:         grid,
 			UiDoneChan:   uiDoneChan,
 		}
 
 		// object to rotate title string
 		title_string := stringRotate{
 			stringPadding: "    ",
-			ticker:        *time.NewTicker(500 * time.Millisecond),
+			ticker:        *time.NewTicker(options.Marquee),
 			increment:     1,
 			doneChan:      display.DoneChan,
 			stringChan:    make(chan string),
@@ -77,58 +113,45 @@ func NewUi(wg *sync.WaitGroup, doneChan <-chan bool, stateChan chan client.State
 
 		// header
 		display.uiHeader = widgets.NewParagraph()
-		header_string := fmt.Sprintf("%s%*s", "VOLUMIO", termWidth-12, time.Now().Format("2006-01-02 15:04"))
-		display.uiHeader.Text = header_string
-		display.uiHeader.Border = show_border
-		display.uiHeader.TextStyle.Fg = ui.ColorMagenta
-		display.uiHeader.TextStyle.Modifier = ui.ModifierBold
 
 		// footer left
 		display.uiFooterLeft = widgets.NewParagraph()
-		display.uiFooterLeft.Border = show_border
-		display.uiFooterLeft.TextStyle.Fg = ui.ColorMagenta
 
 		// footer right
 		display.uiFooterRight = widgets.NewGauge()
-		display.uiFooterRight.Border = show_border
-		display.uiFooterRight.BarColor = ui.ColorMagenta
-		display.uiFooterRight.LabelStyle.Fg = ui.ColorMagenta
 		display.uiFooterRight.Percent = 0
 		display.uiFooterRight.Label = fmt.Sprintf("%d", display.uiFooterRight.Percent)
 
 		// playback details
 		display.uiPlaybackDetails = widgets.NewList()
-		display.uiPlaybackDetails.Border = show_border
 		display.uiPlaybackDetails.SelectedRow = 0
-		display.uiPlaybackDetails.SelectedRowStyle.Fg = ui.ColorYellow
-		display.uiPlaybackDetails.SelectedRowStyle.Modifier = ui.ModifierBold
 
 		// track details
 		display.uiTrackDetails = widgets.NewList()
 		display.uiTrackDetails.Title = "track"
-		display.uiTrackDetails.Border = true
 
 		// player gauge
 		display.uiPlaybackGuage = widgets.NewGauge()
-		display.uiPlaybackGuage.Border = show_border
-		display.uiPlaybackGuage.BarColor = ui.ColorYellow
-		display.uiPlaybackGuage.LabelStyle.Fg = ui.ColorYellow
 		display.uiPlaybackGuage.Percent = 100
 		display.uiPlaybackGuage.Label = fmt.Sprintf("%d", display.uiPlaybackGuage.Percent)
 
-		grid.Set(
-			ui.NewRow(1.0/5, display.uiHeader),
-			ui.NewRow(2.0/5,
-				ui.NewCol(7.0/10, display.uiPlaybackDetails),
-				ui.NewCol(3.0/10, display.uiTrackDetails),
-			),
-			ui.NewRow(1.0/5, display.uiPlaybackGuage),
-			ui.NewRow(1.0/5,
-				ui.NewCol(2.0/3, display.uiFooterLeft),
-				ui.NewCol(1.0/3, display.uiFooterRight),
-			),
-		)
-		ui.Render(grid)
+		// views
+		display.nowPlaying = &nowPlayingView{d: &display}
+		display.queue = newQueueView(&display)
+		display.browser = newBrowserView(&display)
+		display.search = newSearchView(&display)
+		display.playlists = newPlaylistView(&display)
+		display.views = []view{display.nowPlaying, display.queue, display.browser, display.search, display.playlists}
+
+		if err := options.CheckTheme(); err != nil {
+			errorLog.Println(err)
+			options.Theme = DefaultTheme
+		}
+		display.applyTheme(options.Theme)
+
+		display.view = display.nowPlaying
+		display.resize(ui.TerminalDimensions())
+		display.nowPlaying.open()
 		instance = &display
 	})
 	return instance
@@ -143,28 +166,63 @@ func (d *Display) Close() {
 
 func (d *Display) Draw() {
 	clock_ticker := time.NewTicker(time.Second).C
+	network_ticker := time.NewTicker(networkInterval).C
+	d.refreshNetwork()
 	for {
 		select {
 		case e := <-d.uiEventsChan:
-			switch e.ID {
-			case "q", "<C-c>":
-				d.UiDoneChan <- true
+			if e.Type == ui.ResizeEvent {
+				size := e.Payload.(ui.Resize)
+				d.resize(size.Width, size.Height)
+				continue
+			}
+			if d.prompt != nil {
+				d.handlePrompt(e)
+				continue
+			}
+			if d.view.handle(e) {
+				continue
+			}
+			if action, ok := d.Keys[e.ID]; ok {
+				d.execute(action)
 			}
 		case state := <-d.StateChan:
 			if state != d.State {
+				previous := d.State
 				d.State = state
 				// check title string
 				d.stringRotate.update(d.State.Title)
 				// update display
 				d.update()
+				if d.view == d.queue {
+					d.queue.stateChanged(previous)
+				}
 			}
+		case conn_state := <-d.ConnChan:
+			d.connState = conn_state
+			d.uiFooterLeft.Text = d.getFooterString()
+			d.render(d.uiFooterLeft)
 		case title := <-d.stringRotate.stringChan:
 			d.State.Title = title
 			d.uiPlaybackDetails.Rows = d.getPlaybackDetails()
-			ui.Render(d.uiPlaybackDetails)
+			d.render(d.uiPlaybackDetails)
+		case apply := <-d.applyChan:
+			apply()
+		case action := <-d.actionChan():
+			d.execute(action)
 		case <-clock_ticker:
-			d.uiHeader.Text = getHeaderString()
-			ui.Render(d.uiHeader)
+			d.uiHeader.Text = d.getHeaderString()
+			d.render(d.uiHeader)
+			// pushing clients only send changes, the hub moves the position on
+			if d.State.Status == "play" && !d.seeked {
+				d.advance()
+			}
+			d.seeked = false
+			if d.status != "" && time.Since(d.statusTime) > statusTimeout {
+				d.setStatus("")
+			}
+		case <-network_ticker:
+			d.refreshNetwork()
 		case <-d.DoneChan:
 			d.Close()
 		}
@@ -187,6 +245,276 @@ func (d PlayDuration) String() string {
 	return fmt.Sprintf("%02d:%02d", int(minutes), int(seconds))
 }
 
+// run a client command without blocking the ui, failures end up in the status line
+func (d *Display) do(cmd func(ctx context.Context) error) {
+	d.fetch(func(ctx context.Context) (func(), error) {
+		return nil, cmd(ctx)
+	})
+}
+
+// run fn in the background, the function it returns is applied on the
+// ui goroutine so it can safely update widgets
+func (d *Display) fetch(fn func(ctx context.Context) (func(), error)) {
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), commandTimeout)
+		defer cancel()
+		apply, err := fn(ctx)
+		if err != nil {
+			d.report(err)
+			apply = func() { d.setStatus(err.Error()) }
+		}
+		if apply == nil {
+			return
+		}
+		select {
+		case d.applyChan <- apply:
+		case <-d.DoneChan:
+		}
+	}()
+}
+
+// log a failed command and pass it on to ErrChan
+func (d *Display) report(err error) {
+	d.ErrorLog.Println(err)
+	if d.ErrChan != nil {
+		select {
+		case d.ErrChan <- err:
+		default:
+		}
+	}
+}
+
+func (d *Display) setStatus(status string) {
+	d.status = status
+	d.statusTime = time.Now()
+	d.uiFooterLeft.Text = d.getFooterString()
+	d.render(d.uiFooterLeft)
+}
+
+func (d *Display) openPrompt(label string, onSubmit func(string)) {
+	d.prompt = newPrompt(label, onSubmit)
+	d.uiFooterLeft.Text = d.prompt.String()
+	d.render(d.uiFooterLeft)
+}
+
+func (d *Display) handlePrompt(e ui.Event) {
+	if !d.prompt.handle(e) {
+		d.prompt = nil
+	}
+	d.uiFooterLeft.Text = d.getFooterString()
+	d.render(d.uiFooterLeft)
+}
+
+func (d *Display) jumpTo(input string) {
+	position, err := actions.ParsePosition(input)
+	if err != nil {
+		d.setStatus(err.Error())
+		return
+	}
+	d.seek(d.State.SeekTarget(position - d.State.Elapsed()))
+}
+
+func (d *Display) seekBy(delta time.Duration) {
+	d.seek(d.current().SeekTarget(delta))
+}
+
+// seek and update the gauge right away instead of waiting for the next state
+func (d *Display) seek(position time.Duration) {
+	d.State.Seek = int(position.Milliseconds())
+	d.seeked = true
+	d.renderPlaybackGauge()
+
+	d.do(func(ctx context.Context) error {
+		return d.Client.Seek(ctx, position)
+	})
+}
+
+// current is the state interpolated by the hub, the last state received
+// without a runner
+func (d *Display) current() client.State {
+	if d.Runner == nil {
+		return d.State
+	}
+	return d.Runner.State()
+}
+
+// move the position on to the one of the hub
+func (d *Display) advance() {
+	current := d.current()
+	if current.Uri != d.State.Uri {
+		return
+	}
+	d.State.Seek = current.Seek
+	d.renderPlaybackGauge()
+}
+
+func (d *Display) renderPlaybackGauge() {
+	d.uiPlaybackGuage.Percent = d.getElapsedPercent(d.State.Seek, d.State.Duration)
+	d.updatePlaybackGauge()
+	d.render(d.uiPlaybackGuage)
+}
+
+func (d *Display) toggleRandom() {
+	d.State.Random = !d.State.Random
+	d.uiTrackDetails.Rows = d.getTrackDetails()
+	d.render(d.uiTrackDetails)
+
+	random := d.State.Random
+	d.do(func(ctx context.Context) error {
+		return d.Client.SetRandom(ctx, random)
+	})
+}
+
+// cycle repeat through off, all and single
+func (d *Display) cycleRepeat() {
+	switch {
+	case d.State.RepeatSingle:
+		d.State.Repeat, d.State.RepeatSingle = false, false
+		d.do(func(ctx context.Context) error {
+			return d.Client.SetRepeat(ctx, false)
+		})
+	case d.State.Repeat:
+		d.State.RepeatSingle = true
+		d.do(func(ctx context.Context) error {
+			return d.Client.SetRepeatSingle(ctx, true)
+		})
+	default:
+		d.State.Repeat = true
+		d.do(func(ctx context.Context) error {
+			return d.Client.SetRepeat(ctx, true)
+		})
+	}
+	d.uiTrackDetails.Rows = d.getTrackDetails()
+	d.render(d.uiTrackDetails)
+}
+
+// the current item, the title in the state may be rotated by the marquee
+func (d *Display) currentItem() client.QueueItem {
+	item := d.State.QueueItem()
+	item.Name = d.stringRotate.originalString
+	return item
+}
+
+func (d *Display) addFavourite() {
+	p, ok := d.Client.(client.PlaylistInterface)
+	if !ok {
+		d.setStatus(noPlaylistStatus)
+		return
+	}
+	item := d.currentItem()
+	d.fetch(func(ctx context.Context) (func(), error) {
+		if err := p.AddToFavourites(ctx, item); err != nil {
+			return nil, err
+		}
+		return func() { d.setStatus("added to favourites: " + item.Name) }, nil
+	})
+}
+
+// run a bound action, actions with feedback in the display go through
+// its helpers and the others through the runner
+func (d *Display) execute(a actions.Action) {
+	switch a.Name {
+	case actions.Quit:
+		d.UiDoneChan <- true
+	case actions.View:
+		d.showView(a)
+	case actions.NextView:
+		d.nextView()
+	case actions.Theme:
+		if a.Arg == "" || a.Arg == "next" {
+			d.cycleTheme()
+		} else {
+			d.applyTheme(a.Arg)
+		}
+	case actions.Search:
+		d.setView(d.search)
+		d.search.prompt()
+	case actions.JumpTo:
+		d.openPrompt("jump to (mm:ss):", d.jumpTo)
+	case actions.Seek:
+		if a.Relative {
+			d.seekBy(a.Position)
+		} else {
+			d.seek(d.State.SeekTarget(a.Position - d.State.Elapsed()))
+		}
+	case actions.Favourite:
+		d.addFavourite()
+	case actions.SavePreset:
+		if a.Arg == "" {
+			d.openPrompt("save preset (1-9):", d.savePreset)
+		} else {
+			d.savePreset(a.Arg)
+		}
+	case actions.Preset:
+		d.recallPreset(a.Number)
+	case actions.Random:
+		if a.Arg == "" || a.Arg == "toggle" {
+			d.toggleRandom()
+		} else {
+			d.run(a)
+		}
+	case actions.Repeat:
+		if a.Arg == "" || a.Arg == "cycle" {
+			d.cycleRepeat()
+		} else {
+			d.run(a)
+		}
+	default:
+		d.run(a)
+	}
+}
+
+func (d *Display) run(a actions.Action) {
+	if d.Runner == nil {
+		d.setStatus(noRunnerStatus)
+		return
+	}
+	d.do(func(ctx context.Context) error {
+		return d.Runner.Run(ctx, a)
+	})
+}
+
+// ui actions from other inputs, nil blocks forever without a runner
+func (d *Display) actionChan() <-chan actions.Action {
+	if d.Runner == nil {
+		return nil
+	}
+	return d.Runner.UIChan
+}
+
+func (d *Display) recallPreset(slot int) {
+	if d.Runner == nil || d.Runner.Presets == nil {
+		d.setStatus(noPresetsStatus)
+		return
+	}
+	d.fetch(func(ctx context.Context) (func(), error) {
+		preset, err := d.Runner.Presets.Recall(ctx, slot)
+		if err != nil {
+			return nil, err
+		}
+		return func() { d.setStatus(fmt.Sprintf("preset %d: %s", slot, preset.Title)) }, nil
+	})
+}
+
+func (d *Display) savePreset(input string) {
+	if d.Runner == nil || d.Runner.Presets == nil {
+		d.setStatus(noPresetsStatus)
+		return
+	}
+	slot, err := strconv.Atoi(strings.TrimSpace(input))
+	if err != nil {
+		d.setStatus(fmt.Sprintf("invalid preset: %q", input))
+		return
+	}
+	preset, err := d.Runner.Presets.Save(slot)
+	if err != nil {
+		d.report(err)
+		d.setStatus(err.Error())
+		return
+	}
+	d.setStatus(fmt.Sprintf("saved preset %d: %s", slot, preset.Title))
+}
+
 func (d *Display) updatePlaybackGauge() {
 	current_duration := PlayDuration{Duration: time.Duration(d.State.Seek) * time.Millisecond}
 	total_duration := PlayDuration{Duration: time.Duration(d.State.Duration) * time.Second}
@@ -199,22 +527,52 @@ func (d *Display) updatePlaybackGauge() {
 }
 
 func (d *Display) update() {
-	d.uiFooterLeft.Text = d.getIp() + strings.Repeat("/", int(d.getWifiSignalStrength()))
+	d.uiFooterLeft.Text = d.getFooterString()
 	d.uiFooterRight.Percent = d.State.Volume
 	d.uiFooterRight.Label = fmt.Sprintf("%d", d.uiFooterRight.Percent)
 	d.uiTrackDetails.Rows = d.getTrackDetails()
 	d.uiPlaybackGuage.Percent = d.getElapsedPercent(d.State.Seek, d.State.Duration)
 	d.updatePlaybackGauge()
 
-	ui.Render(d.uiFooterLeft, d.uiFooterRight, d.uiTrackDetails, d.uiPlaybackDetails, d.uiPlaybackGuage)
+	d.render(d.uiFooterLeft, d.uiFooterRight, d.uiTrackDetails, d.uiPlaybackDetails, d.uiPlaybackGuage)
+}
+
+// prompt while active, then the status, otherwise network and connection details
+func (d *Display) getFooterString() string {
+	if d.prompt != nil {
+		return d.prompt.String()
+	}
+	if d.status != "" {
+		return d.status
+	}
+	footer := d.network
+	if d.ConnChan != nil {
+		footer += fmt.Sprintf(" [%s]", d.connState)
+	}
+	return footer
 }
 
 func (d *Display) getTrackDetails() []string {
 	if d.State.TrackType == "webradio" {
-		return []string{d.State.BitRate, d.State.SampleRate, d.State.TrackType, d.State.Service}
+		return []string{d.State.BitRate, d.State.SampleRate, d.State.TrackType, d.State.Service, d.getModeString()}
 	} else {
-		return []string{d.State.BitDepth, d.State.SampleRate, d.State.TrackType, d.State.Service}
+		return []string{d.State.BitDepth, d.State.SampleRate, d.State.TrackType, d.State.Service, d.getModeString()}
+	}
+}
+
+// shuffle and repeat indicators
+func (d *Display) getModeString() string {
+	modes := []string{"shuffle", "repeat"}
+	if !d.State.Random {
+		modes[0] = "-------"
+	}
+	switch {
+	case d.State.RepeatSingle:
+		modes[1] = "repeat1"
+	case !d.State.Repeat:
+		modes[1] = "------"
 	}
+	return strings.Join(modes, " ")
 }
 
 func (d *Display) getPlaybackDetails() []string {
@@ -261,27 +619,57 @@ func (s *stringRotate) close() {
 	close(s.stringChan)
 }
 
-func getHeaderString() string {
-	var str string
-	for len(str) < termWidth-25 {
-		str += "/"
+func (d *Display) getHeaderString() string {
+	title := "VOLUMIO"
+	if d.view != d.nowPlaying {
+		title += " " + strings.ToUpper(d.view.name()) + " "
 	}
-	return fmt.Sprintf("%s%s%s", "VOLUMIO", str, time.Now().Format("2006-01-02 15:04"))
+	// small screens only show the time
+	clock := time.Now().Format("2006-01-02 15:04")
+	if d.size == compact {
+		clock = time.Now().Format("15:04")
+	}
+	// the paragraph keeps a column free on either side
+	padding := d.width - 2 - len(title) - len(clock)
+	if padding < 1 {
+		return clock
+	}
+	return title + strings.Repeat("/", padding) + clock
 }
 
-func (d *Display) getIp() string {
+// read the ip and wifi signal in the background and keep them for the
+// footer, reading them runs several processes
+func (d *Display) refreshNetwork() {
+	wifi_iface := d.Options.WifiInterface
+	d.fetch(func(ctx context.Context) (func(), error) {
+		network := getIp(ctx) + strings.Repeat("/", int(getWifiSignalStrength(ctx, wifi_iface)))
+		return func() {
+			if network == d.network {
+				return
+			}
+			d.network = network
+			d.uiFooterLeft.Text = d.getFooterString()
+			d.render(d.uiFooterLeft)
+		}, nil
+	})
+}
+
+func getIp(ctx context.Context) string {
 	iface_cmd := "ip link show | grep 'state UP' | grep -Po '\\b[a-z]{3,}\\d[a-z]\\d\\b|\\b[a-z]{3,}\\d\\b'"
-	iface, _ := exec.Command("bash", "-c", iface_cmd).Output()
+	iface, _ := exec.CommandContext(ctx, "bash", "-c", iface_cmd).Output()
 	iface_str := strings.TrimSuffix(string(iface), "\n")
 	cmd := fmt.Sprintf("ip addr show %s | grep -Po 'inet \\K[\\d.]+'", iface_str)
-	out, _ := exec.Command("bash", "-c", cmd).Output()
+	out, _ := exec.CommandContext(ctx, "bash", "-c", cmd).Output()
 
 	return strings.TrimSuffix(string(out), "\n") + fmt.Sprintf(" (%s)", iface_str)
 }
 
-func (d *Display) getWifiSignalStrength() float64 {
-	cmd := "iw dev wlp5s0 link | grep -Po 'signal: -\\K[\\d]+'"
-	out, _ := exec.Command("bash", "-c", cmd).Output()
+func getWifiSignalStrength(ctx context.Context, wifi_iface string) float64 {
+	if wifi_iface == "" {
+		return 0
+	}
+	cmd := fmt.Sprintf("iw dev %s link | grep -Po 'signal: -\\K[\\d]+'", wifi_iface)
+	out, _ := exec.CommandContext(ctx, "bash", "-c", cmd).Output()
 
 	signal, _ := strconv.Atoi(strings.TrimSuffix(string(out), "\n"))
 
@@ -0,0 +1,353 @@
+package ui
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	ui "github.com/gizak/termui/v3"
+	"github.com/gizak/termui/v3/widgets"
+	"gopkg.in/yaml.v3"
+)
+
+const DefaultTheme = "default"
+
+// Style of a widget, colors are names or 256 color numbers, empty for
+// the terminal default
+type Style struct {
+	Fg         string `yaml:"fg"`
+	Bg         string `yaml:"bg"`
+	Modifier   string `yaml:"modifier"`    // bold, underline and reverse, separated by spaces
+	Border     string `yaml:"border"`      // color of the border and title, fg when empty
+	Bar        string `yaml:"bar"`         // color of gauge bars, fg when empty
+	ShowBorder *bool  `yaml:"show_border"` // overrides the show_border of the theme
+}
+
+// Theme styles every widget of the display
+type Theme struct {
+	Base       string `yaml:"base"`        // built in theme a user theme starts from, default when empty
+	ShowBorder bool   `yaml:"show_border"` // borders of the widgets whose style doesn't set one
+	Header     Style  `yaml:"header"`
+	Footer     Style  `yaml:"footer"`   // network details, status and prompts
+	Volume     Style  `yaml:"volume"`   // volume gauge of the footer
+	Playback   Style  `yaml:"playback"` // album and artist
+	Title      Style  `yaml:"title"`    // the scrolling title
+	Track      Style  `yaml:"track"`    // format and mode details
+	Progress   Style  `yaml:"progress"` // playback gauge
+	List       Style  `yaml:"list"`     // lists of the queue, library, search and playlists
+	Selected   Style  `yaml:"selected"` // selected rows and the active tab
+	Tabs       Style  `yaml:"tabs"`     // library sources
+}
+
+func border(show bool) *bool {
+	return &show
+}
+
+// styles of a theme by yaml key
+var styles = []struct {
+	key   string
+	field func(t *Theme) *Style
+}{
+	{"header", func(t *Theme) *Style { return &t.Header }},
+	{"footer", func(t *Theme) *Style { return &t.Footer }},
+	{"volume", func(t *Theme) *Style { return &t.Volume }},
+	{"playback", func(t *Theme) *Style { return &t.Playback }},
+	{"title", func(t *Theme) *Style { return &t.Title }},
+	{"track", func(t *Theme) *Style { return &t.Track }},
+	{"progress", func(t *Theme) *Style { return &t.Progress }},
+	{"list", func(t *Theme) *Style { return &t.List }},
+	{"selected", func(t *Theme) *Style { return &t.Selected }},
+	{"tabs", func(t *Theme) *Style { return &t.Tabs }},
+}
+
+// yaml keys of a Style
+var styleKeys = map[string]bool{"fg": true, "bg": true, "modifier": true, "border": true, "bar": true, "show_border": true}
+
+// built in themes in the order they are cycled through
+var (
+	themeNames = []string{DefaultTheme, "high-contrast", "monochrome", "solarized"}
+	themes     = map[string]Theme{
+		DefaultTheme: {
+			Header:   Style{Fg: "magenta", Modifier: "bold"},
+			Footer:   Style{Fg: "magenta"},
+			Volume:   Style{Fg: "magenta"},
+			Title:    Style{Fg: "yellow", Modifier: "bold"},
+			Track:    Style{ShowBorder: border(true)},
+			Progress: Style{Fg: "yellow"},
+			List:     Style{ShowBorder: border(true)},
+			Selected: Style{Fg: "yellow", Modifier: "bold"},
+		},
+		"high-contrast": {
+			ShowBorder: true,
+			Header:     Style{Fg: "white", Modifier: "bold"},
+			Footer:     Style{Fg: "white", Modifier: "bold"},
+			Volume:     Style{Fg: "white", Bar: "yellow", Modifier: "bold"},
+			Playback:   Style{Fg: "white"},
+			Title:      Style{Fg: "yellow", Modifier: "bold"},
+			Track:      Style{Fg: "white"},
+			Progress:   Style{Fg: "white", Bar: "yellow", Modifier: "bold"},
+			List:       Style{Fg: "white"},
+			Selected:   Style{Fg: "black", Bg: "yellow", Modifier: "bold"},
+			Tabs:       Style{Fg: "white", ShowBorder: border(false)},
+		},
+		// only modifiers, for terminals without colors, the bars are white
+		// as gauges can't be drawn in reverse
+		"monochrome": {
+			ShowBorder: true,
+			Header:     Style{Modifier: "bold"},
+			Volume:     Style{Bar: "white"},
+			Title:      Style{Modifier: "bold"},
+			Progress:   Style{Bar: "white"},
+			Selected:   Style{Modifier: "reverse"},
+			Tabs:       Style{Modifier: "underline", ShowBorder: border(false)},
+		},
+		// the solarized palette in 256 colors
+		"solarized": {
+			Header:   Style{Fg: "33", Modifier: "bold"},
+			Footer:   Style{Fg: "37"},
+			Volume:   Style{Fg: "37"},
+			Playback: Style{Fg: "244"},
+			Title:    Style{Fg: "136", Modifier: "bold"},
+			Track:    Style{Fg: "244", Border: "240", ShowBorder: border(true)},
+			Progress: Style{Fg: "245", Bar: "33"},
+			List:     Style{Fg: "244", Border: "240", ShowBorder: border(true)},
+			Selected: Style{Fg: "166", Modifier: "bold"},
+			Tabs:     Style{Fg: "240"},
+		},
+	}
+)
+
+var modifiers = map[string]ui.Modifier{
+	"none":      ui.ModifierClear,
+	"bold":      ui.ModifierBold,
+	"underline": ui.ModifierUnderline,
+	"reverse":   ui.ModifierReverse,
+}
+
+func parseModifier(s string) (ui.Modifier, error) {
+	modifier := ui.ModifierClear
+	for _, name := range strings.Fields(s) {
+		m, ok := modifiers[strings.ToLower(name)]
+		if !ok {
+			return ui.ModifierClear, fmt.Errorf("unknown modifier %q, expected bold, underline or reverse", name)
+		}
+		modifier |= m
+	}
+	return modifier, nil
+}
+
+func (s Style) validate() error {
+	for _, c := range []string{s.Fg, s.Bg, s.Border, s.Bar} {
+		if c == "" {
+			continue
+		}
+		if _, err := ParseColor(c); err != nil {
+			return err
+		}
+	}
+	_, err := parseModifier(s.Modifier)
+	return err
+}
+
+func (s Style) text() ui.Style {
+	modifier, _ := parseModifier(s.Modifier)
+	return ui.NewStyle(color(s.Fg), color(s.Bg), modifier)
+}
+
+func (s Style) bar() ui.Color {
+	if s.Bar != "" {
+		return color(s.Bar)
+	}
+	return color(s.Fg)
+}
+
+func (s Style) block(b *ui.Block, show_border bool) {
+	if s.ShowBorder != nil {
+		show_border = *s.ShowBorder
+	}
+	b.Border = show_border
+	style := s.text()
+	if s.Border != "" {
+		style.Fg = color(s.Border)
+	}
+	b.BorderStyle = ui.NewStyle(style.Fg, style.Bg)
+	b.TitleStyle = style
+}
+
+func (s Style) paragraph(p *widgets.Paragraph, show_border bool) {
+	s.block(&p.Block, show_border)
+	p.TextStyle = s.text()
+}
+
+func (s Style) gauge(g *widgets.Gauge, show_border bool) {
+	s.block(&g.Block, show_border)
+	g.BarColor = s.bar()
+	g.LabelStyle = s.text()
+}
+
+func (s Style) list(l *widgets.List, selected Style, show_border bool) {
+	s.block(&l.Block, show_border)
+	l.TextStyle = s.text()
+	l.SelectedRowStyle = selected.text()
+}
+
+// Validate checks the colors and modifiers of every style
+func (t Theme) Validate() error {
+	for _, style := range styles {
+		if err := style.field(&t).validate(); err != nil {
+			return fmt.Errorf("%s: %w", style.key, err)
+		}
+	}
+	return nil
+}
+
+// clone copies the theme along with the show_border of its styles, the
+// decoder writes through those pointers
+func (t Theme) clone() Theme {
+	for _, style := range styles {
+		if s := style.field(&t); s.ShowBorder != nil {
+			s.ShowBorder = border(*s.ShowBorder)
+		}
+	}
+	return t
+}
+
+// UnmarshalYAML starts user themes from their base, keys missing from
+// the config keep the style of the base
+func (t *Theme) UnmarshalYAML(node *yaml.Node) error {
+	var base struct {
+		Base string `yaml:"base"`
+	}
+	if err := node.Decode(&base); err != nil {
+		return err
+	}
+	if base.Base == "" {
+		base.Base = DefaultTheme
+	}
+	builtin, ok := themes[base.Base]
+	if !ok {
+		return fmt.Errorf("line %d: unknown base theme %q, expected one of %s", node.Line, base.Base, strings.Join(themeNames, ", "))
+	}
+	// the decoder of the config doesn't check the fields of nested
+	// decoders
+	if err := checkFields(node); err != nil {
+		return err
+	}
+	type plain Theme
+	*t = builtin.clone()
+	return node.Decode((*plain)(t))
+}
+
+// checkFields reports keys of the theme node and its styles that are
+// neither a style nor a setting of the theme
+func checkFields(node *yaml.Node) error {
+	if node.Kind != yaml.MappingNode {
+		return nil
+	}
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		key, value := node.Content[i], node.Content[i+1]
+		switch {
+		case key.Value == "base" || key.Value == "show_border":
+		case isStyle(key.Value):
+			if err := checkStyleFields(value); err != nil {
+				return err
+			}
+		default:
+			return fmt.Errorf("line %d: field %s not found in theme", key.Line, key.Value)
+		}
+	}
+	return nil
+}
+
+func checkStyleFields(node *yaml.Node) error {
+	if node.Kind != yaml.MappingNode {
+		return nil
+	}
+	for i := 0; i < len(node.Content); i += 2 {
+		if key := node.Content[i]; !styleKeys[key.Value] {
+			return fmt.Errorf("line %d: field %s not found in style", key.Line, key.Value)
+		}
+	}
+	return nil
+}
+
+func isStyle(key string) bool {
+	for _, style := range styles {
+		if style.key == key {
+			return true
+		}
+	}
+	return false
+}
+
+// ThemeNames are the built in themes followed by the user themes of o
+func (o Options) ThemeNames() []string {
+	names := append([]string(nil), themeNames...)
+	var user []string
+	for name := range o.Themes {
+		if _, ok := themes[name]; !ok {
+			user = append(user, name)
+		}
+	}
+	sort.Strings(user)
+	return append(names, user...)
+}
+
+// theme looks up a theme by name, user themes replace built in ones of
+// the same name
+func (o Options) theme(name string) (Theme, bool) {
+	if t, ok := o.Themes[name]; ok {
+		return t, true
+	}
+	t, ok := themes[name]
+	return t, ok
+}
+
+// CheckTheme checks that the theme of o exists
+func (o Options) CheckTheme() error {
+	if _, ok := o.theme(o.Theme); !ok {
+		return fmt.Errorf("unknown theme %q, expected one of %s", o.Theme, strings.Join(o.ThemeNames(), ", "))
+	}
+	return nil
+}
+
+// applyTheme styles the widgets and lays the screen out again
+func (d *Display) applyTheme(name string) {
+	t, ok := d.Options.theme(name)
+	if !ok {
+		d.setStatus(fmt.Sprintf("unknown theme %q", name))
+		return
+	}
+	d.theme = name
+
+	t.Header.paragraph(d.uiHeader, t.ShowBorder)
+	t.Footer.paragraph(d.uiFooterLeft, t.ShowBorder)
+	t.Volume.gauge(d.uiFooterRight, t.ShowBorder)
+	t.Playback.list(d.uiPlaybackDetails, t.Title, t.ShowBorder)
+	t.Track.list(d.uiTrackDetails, t.Track, t.ShowBorder)
+	t.Progress.gauge(d.uiPlaybackGuage, t.ShowBorder)
+	for _, list := range []*widgets.List{d.queue.list, d.browser.list, d.search.list, d.playlists.list} {
+		t.List.list(list, t.Selected, t.ShowBorder)
+	}
+	t.Tabs.block(&d.browser.tabs.Block, t.ShowBorder)
+	d.browser.tabs.InactiveTabStyle = t.Tabs.text()
+	d.browser.tabs.ActiveTabStyle = t.Selected.text()
+
+	if d.grid.Items != nil {
+		d.layout()
+	}
+}
+
+// cycleTheme switches to the theme after the current one
+func (d *Display) cycleTheme() {
+	names := d.Options.ThemeNames()
+	next := names[0]
+	for i := range names {
+		if names[i] == d.theme {
+			next = names[(i+1)%len(names)]
+			break
+		}
+	}
+	d.applyTheme(next)
+	d.setStatus("theme: " + next)
+}
@@ -0,0 +1,61 @@
+package ui
+
+import (
+	"reflect"
+	"testing"
+
+	ui "github.com/gizak/termui/v3"
+	"gopkg.in/yaml.v3"
+)
+
+func Test_Themes(t *testing.T) {
+	for _, name := range themeNames {
+		if err := themes[name].Validate(); err != nil {
+			t.Errorf("%s: %v", name, err)
+		}
+	}
+
+	var options Options
+	data := `
+theme: tft
+themes:
+  tft: {base: high-contrast, header: {fg: "202", modifier: bold underline}}
+  default: {show_border: true}
+`
+	if err := yaml.Unmarshal([]byte(data), &options); err != nil {
+		t.Fatal(err)
+	}
+	// user themes follow the built in ones, replacing those of the same name
+	want := []string{"default", "high-contrast", "monochrome", "solarized", "tft"}
+	if names := options.ThemeNames(); !reflect.DeepEqual(names, want) {
+		t.Fatalf("expected %v, got %v", want, names)
+	}
+	if d, _ := options.theme("default"); !d.ShowBorder || d.Header.Fg != "magenta" {
+		t.Errorf("unexpected default theme %+v", d)
+	}
+
+	// overriding a style of the base leaves the built in theme alone
+	var list Theme
+	if err := yaml.Unmarshal([]byte("list: {show_border: false}"), &list); err != nil {
+		t.Fatal(err)
+	}
+	if *list.List.ShowBorder || !*themes[DefaultTheme].List.ShowBorder {
+		t.Errorf("list border of the default theme changed")
+	}
+
+	tft, _ := options.theme("tft")
+	if tft.Selected != themes["high-contrast"].Selected {
+		t.Errorf("tft doesn't extend high-contrast: %+v", tft)
+	}
+	style := tft.Header.text()
+	if style.Fg != ui.Color(202) || style.Modifier != ui.ModifierBold|ui.ModifierUnderline {
+		t.Errorf("unexpected header style %+v", style)
+	}
+	if err := options.CheckTheme(); err != nil {
+		t.Error(err)
+	}
+	options.Theme = "neon"
+	if err := options.CheckTheme(); err == nil {
+		t.Error("expected an unknown theme")
+	}
+}
@@ -0,0 +1,169 @@
+package ui
+
+import (
+	"fmt"
+
+	"volumgui/actions"
+
+	ui "github.com/gizak/termui/v3"
+)
+
+// screen shown between header and footer
+type view interface {
+	name() string             // name shown in the header
+	rows() []interface{}      // grid rows, together they take 3/5 of the height
+	drawables() []ui.Drawable // widgets rendered by the view
+	handle(e ui.Event) bool   // handle a key, returns false if the key was not used
+	open()                    // called when the view is shown
+}
+
+// now playing screen with playback details and progress
+type nowPlayingView struct {
+	d *Display
+}
+
+func (v *nowPlayingView) name() string {
+	return "now playing"
+}
+
+func (v *nowPlayingView) rows() []interface{} {
+	details := ui.NewRow(2.0/5,
+		ui.NewCol(7.0/10, v.d.uiPlaybackDetails),
+		ui.NewCol(3.0/10, v.d.uiTrackDetails),
+	)
+	switch v.d.size {
+	case compact:
+		details = ui.NewRow(2.0/5, v.d.uiPlaybackDetails)
+	case wide:
+		details = ui.NewRow(2.0/5,
+			ui.NewCol(3.0/4, v.d.uiPlaybackDetails),
+			ui.NewCol(1.0/4, v.d.uiTrackDetails),
+		)
+	}
+	return []interface{}{details, ui.NewRow(1.0/5, v.d.uiPlaybackGuage)}
+}
+
+func (v *nowPlayingView) drawables() []ui.Drawable {
+	if v.d.size == compact {
+		return []ui.Drawable{v.d.uiPlaybackDetails, v.d.uiPlaybackGuage}
+	}
+	return []ui.Drawable{v.d.uiPlaybackDetails, v.d.uiTrackDetails, v.d.uiPlaybackGuage}
+}
+
+func (v *nowPlayingView) handle(e ui.Event) bool {
+	return false
+}
+
+func (v *nowPlayingView) open() {}
+
+// show a view and lay out the grid around it
+func (d *Display) setView(v view) {
+	d.view = v
+	d.layout()
+	v.open()
+}
+
+// switch to the view at index, views are numbered from 1 on the keyboard
+func (d *Display) selectView(index int) {
+	if index >= 0 && index < len(d.views) && d.views[index] != d.view {
+		d.setView(d.views[index])
+	}
+}
+
+// show a view by number or by name
+func (d *Display) showView(a actions.Action) {
+	if a.Number > 0 {
+		d.selectView(a.Number - 1)
+		return
+	}
+	for i := range d.views {
+		if d.views[i].name() == a.Arg {
+			d.selectView(i)
+			return
+		}
+	}
+	d.setStatus(fmt.Sprintf("unknown view %q", a.Arg))
+}
+
+func (d *Display) nextView() {
+	for i := range d.views {
+		if d.views[i] == d.view {
+			d.setView(d.views[(i+1)%len(d.views)])
+			return
+		}
+	}
+}
+
+// layouts by terminal size
+type layoutSize int
+
+const (
+	compact layoutSize = iota // small displays, the track details are hidden
+	normal
+	wide
+)
+
+const (
+	compactWidth  = 60 // terminals narrower or lower than this are compact
+	compactHeight = 12
+	wideWidth     = 120 // terminals at least this wide are wide
+)
+
+func sizeOf(width int, height int) layoutSize {
+	switch {
+	case width < compactWidth || height < compactHeight:
+		return compact
+	case width >= wideWidth:
+		return wide
+	default:
+		return normal
+	}
+}
+
+// fit the grid to the terminal and lay it out again
+func (d *Display) resize(width int, height int) {
+	d.width, d.height = width, height
+	d.size = sizeOf(width, height)
+	d.grid.SetRect(0, 0, width, height)
+	d.layout()
+}
+
+func (d *Display) layout() {
+	d.uiHeader.Text = d.getHeaderString()
+	// the volume gauge takes a fixed share of the footer
+	footer := 2.0 / 3
+	switch d.size {
+	case compact:
+		footer = 1.0 / 2
+	case wide:
+		footer = 3.0 / 4
+	}
+	rows := []interface{}{ui.NewRow(1.0/5, d.uiHeader)}
+	rows = append(rows, d.view.rows()...)
+	rows = append(rows, ui.NewRow(1.0/5,
+		ui.NewCol(footer, d.uiFooterLeft),
+		ui.NewCol(1-footer, d.uiFooterRight),
+	))
+	// Set appends to the items of the previous layout
+	d.grid.Items = nil
+	d.grid.Set(rows...)
+	ui.Clear()
+	ui.Render(d.grid)
+}
+
+// render the items that are on screen, widgets of hidden views are skipped
+func (d *Display) render(items ...ui.Drawable) {
+	visible := append([]ui.Drawable{d.uiHeader, d.uiFooterLeft, d.uiFooterRight}, d.view.drawables()...)
+	var render []ui.Drawable
+	for _, item := range items {
+		for _, v := range visible {
+			if item == v {
+				render = append(render, item)
+				break
+			}
+		}
+	}
+	if len(render) > 0 {
+		ui.Render(render...)
+	}
+}
@@ -0,0 +1,39 @@
+package ui
+
+import (
+	"strings"
+	"testing"
+)
+
+func Test_Layout(t *testing.T) {
+	sizes := []struct {
+		width, height int
+		want          layoutSize
+	}{
+		{40, 30, compact},
+		{100, 10, compact},
+		{80, 24, normal},
+		{119, 40, normal},
+		{160, 48, wide},
+	}
+	for _, s := range sizes {
+		if got := sizeOf(s.width, s.height); got != s.want {
+			t.Errorf("%dx%d: expected %d, got %d", s.width, s.height, s.want, got)
+		}
+	}
+
+	// the header fills the width inside the paragraph
+	for _, width := range []int{30, 80, 200} {
+		d := Display{width: width, size: sizeOf(width, 24), nowPlaying: &nowPlayingView{}}
+		d.view = d.nowPlaying
+		header := d.getHeaderString()
+		if len(header) != width-2 || !strings.HasPrefix(header, "VOLUMIO/") {
+			t.Errorf("%d: unexpected header %q", width, header)
+		}
+	}
+	d := Display{width: 12, size: compact, nowPlaying: &nowPlayingView{}}
+	d.view = d.nowPlaying
+	if header := d.getHeaderString(); len(header) != len("15:04") {
+		t.Errorf("expected only the time, got %q", header)
+	}
+}
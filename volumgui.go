@@ -1,14 +1,28 @@
 package main
 
 import (
+	"context"
+	"flag"
+	"fmt"
 	"log"
 	"os"
 	"os/signal"
 	"sync"
 	"syscall"
 	"time"
+	"volumgui/actions"
+	"volumgui/bindings"
 	"volumgui/client"
+	"volumgui/config"
+	"volumgui/control"
+	"volumgui/leds"
+	"volumgui/mpris"
+	"volumgui/mqtt"
+	"volumgui/presets"
 	"volumgui/ui"
+	"volumgui/web"
+
+	"github.com/godbus/dbus/v5"
 )
 
 var (
@@ -17,50 +31,142 @@ var (
 )
 
 type app struct {
+	Config     *config.Config
 	Wait       *sync.WaitGroup
 	DoneChan   chan bool
 	UiDoneChan chan bool
-	Client     client.CmdClient
+	Client     client.ClientInterface
+	Hub        *client.StateHub
+	Presets    *presets.Presets
+	Runner     *actions.Runner
+	ErrChan    chan error // failed commands and polls of the ui, inputs and actions, shown by the leds
 }
 
-func init() {
-	logFile, err := os.OpenFile("volumguiLogs.txt", os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0666)
-	if err != nil {
-		log.Fatal(err)
+func main() {
+	flag.Usage = func() {
+		fmt.Fprintln(flag.CommandLine.Output(), commandUsage)
+		flag.PrintDefaults()
 	}
-	InfoLog = log.New(logFile, "INFO: ", log.Ldate|log.Ltime|log.Lshortfile)
-	ErrorLog = log.New(logFile, "ERROR: ", log.Ldate|log.Ltime|log.Lshortfile)
+	loader := config.NewLoader(flag.CommandLine)
+	flag.Parse()
 
-	InfoLog.Println("initialized.")
-}
-
-func main() {
+	// invalid settings are reported before anything starts
+	cfg, err := loader.Load(os.LookupEnv)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+	}
+	if flag.Arg(0) == "config" {
+		os.Exit(configCommand(cfg, flag.Args()[1:], os.Stdout, os.Stderr))
+	}
+	if err := openLogs(cfg.LogFile); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+	}
 
 	wg := sync.WaitGroup{}
 	done_chan := make(chan bool)
 	ui_done_chan := make(chan bool)
-	client := client.NewCmdClient(&wg, done_chan, InfoLog, ErrorLog)
 
 	app := app{
+		Config:     cfg,
 		Wait:       &wg,
 		DoneChan:   done_chan,
 		UiDoneChan: ui_done_chan,
-		Client:     client,
+		ErrChan:    make(chan error, 1),
 	}
 
-	go func() {
-		poll_ticker := time.NewTicker(time.Second).C
-		for {
-			select {
-			case <-poll_ticker:
-				client.GetState()
-			case <-app.DoneChan:
-				return
-			}
+	if err := app.newClient(cfg.Backend, cfg.Host); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+	}
+	app.Client.Connect()
+
+	// clients pushing their state changes don't need to be polled
+	if pusher, ok := app.Client.(client.PushInterface); !ok || !pusher.PushesState() {
+		go app.pollState()
+	}
+
+	// the ui and presets share the client state
+	go app.Hub.Run()
+
+	app.Presets, err = presets.NewPresets(cfg.Presets, app.Client, app.Hub.Current, InfoLog, ErrorLog)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+	}
+	app.Runner = actions.NewRunner(app.Client, app.Hub.Current, app.Presets, InfoLog, ErrorLog)
+	app.Runner.ErrChan = app.ErrChan
+
+	// subcommands run without the display
+	if flag.NArg() > 0 {
+		os.Exit(app.runCommand(flag.Args(), os.Stdout, os.Stderr))
+	}
+
+	bindings_config, err := bindings.Load(cfg.Bindings)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+	}
+	inputs := bindings.NewInputs(bindings_config, app.Runner, done_chan, InfoLog, ErrorLog)
+	if err := inputs.Start(); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+	}
+
+	led_config, err := leds.Load(cfg.Leds)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+	}
+	if led_config != nil {
+		mapper, err := leds.Open(*led_config, app.Presets.Slot, ErrorLog)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(2)
 		}
-	}()
+		go mapper.Run(app.Hub.Subscribe(), app.Hub.SubscribeConn(), app.ErrChan, done_chan)
+	}
+
+	if cfg.Http != "" {
+		server := web.NewServer(cfg.Http, app.Hub, app.Runner, done_chan, InfoLog, ErrorLog)
+		listener, err := server.Listen()
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(2)
+		}
+		go server.Serve(listener)
+	}
 
-	ui := ui.NewUi(&wg, done_chan, client.StateChan, ui_done_chan, InfoLog, ErrorLog)
+	if cfg.Control != "" {
+		server := control.NewServer(cfg.Control, app.Hub, app.Runner, done_chan, InfoLog, ErrorLog)
+		listener, err := server.Listen()
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(2)
+		}
+		go server.Serve(listener)
+	}
+
+	if cfg.Mqtt.Broker != "" {
+		mqtt_config := mqtt.Config{Broker: cfg.Mqtt.Broker, Prefix: cfg.Mqtt.Prefix, Discovery: cfg.Mqtt.Discovery}
+		bridge, err := mqtt.NewBridge(mqtt_config, app.Hub, app.Runner, done_chan, InfoLog, ErrorLog)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(2)
+		}
+		go bridge.Run()
+	}
+
+	// a missing session bus only costs the media keys
+	if cfg.Mpris {
+		if err := app.startMpris(cfg.Host); err != nil {
+			ErrorLog.Println(err)
+		}
+	}
+
+	ui := ui.NewUi(&wg, done_chan, app.Hub.Subscribe(), app.Hub.SubscribeConn(), app.Client, app.Runner, bindings_config.KeyMap(), cfg.Ui, ui_done_chan, InfoLog, ErrorLog)
+	ui.ErrChan = app.ErrChan
 	go ui.Draw()
 
 	go app.listenForShutdown()
@@ -68,6 +174,84 @@ func main() {
 	select {}
 }
 
+// append the info and error logs to path
+func openLogs(path string) error {
+	logFile, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0666)
+	if err != nil {
+		return err
+	}
+	InfoLog = log.New(logFile, "INFO: ", log.Ldate|log.Ltime|log.Lshortfile)
+	ErrorLog = log.New(logFile, "ERROR: ", log.Ldate|log.Ltime|log.Lshortfile)
+
+	InfoLog.Println("initialized.")
+	return nil
+}
+
+// create the client for the selected backend and the hub sharing its
+// state with the ui, presets and actions. Relative seeks of the client
+// start from the position the hub interpolates
+func (app *app) newClient(backend string, host string) error {
+	switch backend {
+	case "cmd":
+		cmd_client := client.NewCmdClient(app.Wait, app.DoneChan, InfoLog, ErrorLog)
+		app.Client = cmd_client
+		app.Hub = client.NewStateHub(cmd_client.StateChan, nil, app.DoneChan)
+		cmd_client.Current = app.Hub.Current
+	case "socket":
+		sock_client, err := client.NewClient(host, app.Wait, InfoLog, ErrorLog)
+		if err != nil {
+			return err
+		}
+		app.Client = sock_client
+		app.Hub = client.NewStateHub(sock_client.StateChan, sock_client.ConnChan, app.DoneChan)
+		sock_client.Current = app.Hub.Current
+	case "rest":
+		rest_client := client.NewRestClient(host, app.Wait, app.DoneChan, InfoLog, ErrorLog)
+		app.Client = rest_client
+		app.Hub = client.NewStateHub(rest_client.StateChan, nil, app.DoneChan)
+		rest_client.Current = app.Hub.Current
+	default:
+		return fmt.Errorf("unknown backend %q, expected cmd, socket or rest", backend)
+	}
+	return nil
+}
+
+func (app *app) startMpris(host string) error {
+	conn, err := dbus.ConnectSessionBus()
+	if err != nil {
+		return fmt.Errorf("mpris: %w", err)
+	}
+	player := mpris.NewMpris(conn, app.Hub, app.Runner, host, app.DoneChan, InfoLog, ErrorLog)
+	if err := player.Start(); err != nil {
+		conn.Close()
+		return err
+	}
+	go player.Run()
+	return nil
+}
+
+// poll the client state right away and then every poll interval
+func (app *app) pollState() {
+	poll_ticker := time.NewTicker(app.Config.PollInterval).C
+	for {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		if err := app.Client.GetState(ctx); err != nil {
+			ErrorLog.Println(err)
+			select {
+			case app.ErrChan <- err:
+			default:
+			}
+		}
+		cancel()
+
+		select {
+		case <-poll_ticker:
+		case <-app.DoneChan:
+			return
+		}
+	}
+}
+
 // catch and handle graceful shutdown
 func (app *app) listenForShutdown() {
 	quit := make(chan os.Signal, 1)
@@ -87,6 +271,7 @@ func (app *app) listenForShutdown() {
 func (app *app) shutdown() {
 	app.Wait.Wait()
 	app.DoneChan <- true
+	app.Client.Close()
 	InfoLog.Println("closing channels...")
 	close(app.DoneChan)
 	close(app.UiDoneChan)
@@ -7,6 +7,7 @@ import (
 	"sync"
 	"testing"
 	"time"
+	"volumgui/bindings"
 	"volumgui/client"
 	"volumgui/ui"
 )
@@ -21,11 +22,12 @@ func Test_App(m *testing.T) {
 		UiDoneChan: make(chan bool),
 	}
 
-	testApp.Client = client.CmdClient{
+	testClient := &client.CmdClient{
 		DoneChan:  testApp.DoneChan,
 		StateChan: make(chan client.State),
 		Wait:      testApp.Wait,
 	}
+	testApp.Client = testClient
 
 	state := client.State{
 		Artist:     "Cream",
@@ -41,7 +43,7 @@ func Test_App(m *testing.T) {
 	}
 
 	logger := log.New(io.Discard, "", 0)
-	ui := ui.NewUi(testApp.Wait, testApp.DoneChan, testApp.Client.StateChan, testApp.UiDoneChan, logger, logger)
+	ui := ui.NewUi(testApp.Wait, testApp.DoneChan, testClient.StateChan, nil, testApp.Client, nil, bindings.DefaultKeys(), ui.DefaultOptions(), testApp.UiDoneChan, logger, logger)
 	go ui.Draw()
 
 	go func() {
@@ -51,7 +53,7 @@ func Test_App(m *testing.T) {
 			select {
 			case <-poll_ticker:
 				state.Seek = count * 1000
-				testApp.Client.StateChan <- state
+				testClient.StateChan <- state
 				count++
 			case <-testApp.DoneChan:
 				return
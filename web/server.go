@@ -0,0 +1,216 @@
+package web
+
+import (
+	"context"
+	"embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"log"
+	"mime"
+	"net"
+	"net/http"
+	"net/url"
+	"time"
+
+	"volumgui/actions"
+	"volumgui/client"
+)
+
+const (
+	actionTimeout  = 5 * time.Second
+	keepAlive      = 15 * time.Second // comment sent to idle event streams
+	maxRequestSize = 4096
+)
+
+//go:embed static
+var static embed.FS
+
+// Server serves the dashboard, the state as server sent events and runs
+// the posted actions
+type Server struct {
+	Addr     string
+	Hub      *client.StateHub
+	Runner   *actions.Runner
+	DoneChan <-chan bool
+	InfoLog  *log.Logger
+	ErrorLog *log.Logger
+}
+
+func NewServer(addr string, hub *client.StateHub, runner *actions.Runner, done_chan <-chan bool, info_log *log.Logger, error_log *log.Logger) *Server {
+	return &Server{
+		Addr:     addr,
+		Hub:      hub,
+		Runner:   runner,
+		DoneChan: done_chan,
+		InfoLog:  info_log,
+		ErrorLog: error_log,
+	}
+}
+
+// status sent with every state
+type status struct {
+	client.State
+	Conn string `json:"conn"`
+}
+
+func (s *Server) Handler() http.Handler {
+	files, err := fs.Sub(static, "static")
+	if err != nil {
+		panic(err)
+	}
+	mux := http.NewServeMux()
+	mux.Handle("/", http.FileServer(http.FS(files)))
+	mux.HandleFunc("/api/state", s.state)
+	mux.HandleFunc("/api/events", s.events)
+	mux.HandleFunc("/api/action", s.action)
+	return mux
+}
+
+// Listen binds Addr, the dashboard is served once Serve runs
+func (s *Server) Listen() (net.Listener, error) {
+	l, err := net.Listen("tcp", s.Addr)
+	if err != nil {
+		return nil, fmt.Errorf("web: %w", err)
+	}
+	return l, nil
+}
+
+// Serve handles requests on l until DoneChan is closed
+func (s *Server) Serve(l net.Listener) {
+	server := http.Server{Handler: s.Handler(), ErrorLog: s.ErrorLog}
+	go func() {
+		<-s.DoneChan
+		ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+		defer cancel()
+		server.Shutdown(ctx)
+	}()
+	s.InfoLog.Printf("serving the dashboard on %s", l.Addr())
+	if err := server.Serve(l); !errors.Is(err, http.ErrServerClosed) {
+		s.ErrorLog.Println(err)
+	}
+}
+
+func (s *Server) status() status {
+	return status{State: s.Hub.Current(), Conn: s.Hub.Conn().String()}
+}
+
+func (s *Server) state(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	writeJSON(w, http.StatusOK, s.status())
+}
+
+// events streams the status on every state or connection change
+func (s *Server) events(w http.ResponseWriter, r *http.Request) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "streaming unsupported", http.StatusInternalServerError)
+		return
+	}
+	states := s.Hub.Subscribe()
+	defer s.Hub.Unsubscribe(states)
+	conns := s.Hub.SubscribeConn()
+	defer s.Hub.UnsubscribeConn(conns)
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	ticker := time.NewTicker(keepAlive)
+	defer ticker.Stop()
+
+	for {
+		if err := writeEvent(w, s.status()); err != nil {
+			return
+		}
+		flusher.Flush()
+
+		select {
+		case <-states:
+		case <-conns:
+		case <-ticker.C:
+			if _, err := io.WriteString(w, ": keep-alive\n\n"); err != nil {
+				return
+			}
+		case <-r.Context().Done():
+			return
+		case <-s.DoneChan:
+			return
+		}
+	}
+}
+
+// action runs the action posted as {"action": "volume +5"}. Forms of
+// other sites can't post json, and scripts of other sites are turned
+// away by their Origin
+func (s *Server) action(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if media, _, err := mime.ParseMediaType(r.Header.Get("Content-Type")); err != nil || media != "application/json" {
+		writeError(w, http.StatusUnsupportedMediaType, errors.New("expected application/json"))
+		return
+	}
+	if !sameOrigin(r) {
+		writeError(w, http.StatusForbidden, fmt.Errorf("origin %q not allowed", r.Header.Get("Origin")))
+		return
+	}
+	var request struct {
+		Action actions.Action `json:"action"`
+	}
+	if err := json.NewDecoder(io.LimitReader(r.Body, maxRequestSize)).Decode(&request); err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	// the display isn't reachable from here
+	if request.Action.Name == "" || request.Action.UI() {
+		writeError(w, http.StatusBadRequest, fmt.Errorf("action %q not available", request.Action))
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), actionTimeout)
+	defer cancel()
+	if err := s.Runner.Run(ctx, request.Action); err != nil {
+		s.ErrorLog.Println(err)
+		code := http.StatusBadGateway
+		if errors.Is(err, client.ErrUnsupported) {
+			code = http.StatusNotImplemented
+		}
+		writeError(w, code, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]bool{"ok": true})
+}
+
+// requests without an Origin don't come from a browser page
+func sameOrigin(r *http.Request) bool {
+	origin := r.Header.Get("Origin")
+	if origin == "" {
+		return true
+	}
+	u, err := url.Parse(origin)
+	return err == nil && u.Host == r.Host
+}
+
+func writeEvent(w io.Writer, v any) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(w, "data: %s\n\n", data)
+	return err
+}
+
+func writeJSON(w http.ResponseWriter, code int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	json.NewEncoder(w).Encode(v)
+}
+
+func writeError(w http.ResponseWriter, code int, err error) {
+	writeJSON(w, code, map[string]string{"error": err.Error()})
+}
@@ -0,0 +1,168 @@
+package web
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"io"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"volumgui/actions"
+	"volumgui/client"
+)
+
+// client recording its calls
+type callClient struct {
+	client.ClientInterface
+	calls chan string
+}
+
+func (c *callClient) Next(ctx context.Context) error { c.calls <- "next"; return nil }
+
+func newTestServer(t *testing.T) (*httptest.Server, *callClient, chan client.State) {
+	t.Helper()
+	state_chan := make(chan client.State)
+	done_chan := make(chan bool)
+	hub := client.NewStateHub(state_chan, nil, done_chan)
+	go hub.Run()
+
+	c := callClient{calls: make(chan string, 1)}
+	logger := log.New(io.Discard, "", 0)
+	runner := actions.NewRunner(&c, hub.Current, nil, logger, logger)
+	s := NewServer(":0", hub, runner, done_chan, logger, logger)
+	server := httptest.NewServer(s.Handler())
+	t.Cleanup(func() {
+		close(done_chan)
+		server.Close()
+	})
+	return server, &c, state_chan
+}
+
+func post(t *testing.T, url string, body string) (int, map[string]any) {
+	t.Helper()
+	res, err := http.Post(url, "application/json", strings.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer res.Body.Close()
+	var reply map[string]any
+	if err := json.NewDecoder(res.Body).Decode(&reply); err != nil {
+		t.Fatal(err)
+	}
+	return res.StatusCode, reply
+}
+
+func Test_ServerDashboard(t *testing.T) {
+	server, _, _ := newTestServer(t)
+	for _, path := range []string{"/", "/app.js", "/style.css"} {
+		res, err := http.Get(server.URL + path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		res.Body.Close()
+		if res.StatusCode != http.StatusOK {
+			t.Errorf("%s: status %d", path, res.StatusCode)
+		}
+	}
+}
+
+func Test_ServerAction(t *testing.T) {
+	server, c, _ := newTestServer(t)
+
+	if code, reply := post(t, server.URL+"/api/action", `{"action": "next"}`); code != http.StatusOK || reply["ok"] != true {
+		t.Fatalf("unexpected reply %d %v", code, reply)
+	}
+	if call := <-c.calls; call != "next" {
+		t.Fatalf("expected next, got %s", call)
+	}
+
+	for _, body := range []string{`{"action": "dance"}`, `{"action": "quit"}`, `{}`, `next`} {
+		if code, reply := post(t, server.URL+"/api/action", body); code != http.StatusBadRequest || reply["error"] == nil {
+			t.Errorf("%s: unexpected reply %d %v", body, code, reply)
+		}
+	}
+}
+
+func Test_ServerEvents(t *testing.T) {
+	server, _, state_chan := newTestServer(t)
+
+	res, err := http.Get(server.URL + "/api/events")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer res.Body.Close()
+	if ct := res.Header.Get("Content-Type"); ct != "text/event-stream" {
+		t.Fatalf("unexpected content type %q", ct)
+	}
+
+	go func() { state_chan <- client.State{Title: "White Room", Volume: 40} }()
+	lines := bufio.NewScanner(res.Body)
+	deadline := time.AfterFunc(5*time.Second, func() { res.Body.Close() })
+	defer deadline.Stop()
+	for lines.Scan() {
+		data, ok := strings.CutPrefix(lines.Text(), "data: ")
+		if !ok {
+			continue
+		}
+		var s status
+		if err := json.Unmarshal([]byte(data), &s); err != nil {
+			t.Fatal(err)
+		}
+		if s.Conn != "connected" {
+			t.Fatalf("unexpected connection %q", s.Conn)
+		}
+		if s.Title == "White Room" {
+			return
+		}
+	}
+	t.Fatal("state never streamed")
+}
+
+func Test_ServerActionOrigin(t *testing.T) {
+	server, c, _ := newTestServer(t)
+
+	send := func(content_type string, origin string) int {
+		t.Helper()
+		req, err := http.NewRequest(http.MethodPost, server.URL+"/api/action", strings.NewReader(`{"action": "next"}`))
+		if err != nil {
+			t.Fatal(err)
+		}
+		req.Header.Set("Content-Type", content_type)
+		if origin != "" {
+			req.Header.Set("Origin", origin)
+		}
+		res, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		res.Body.Close()
+		return res.StatusCode
+	}
+
+	if code := send("text/plain", ""); code != http.StatusUnsupportedMediaType {
+		t.Errorf("text/plain: unexpected status %d", code)
+	}
+	if code := send("application/json", "http://evil.example"); code != http.StatusForbidden {
+		t.Errorf("foreign origin: unexpected status %d", code)
+	}
+	if code := send("application/json", "null"); code != http.StatusForbidden {
+		t.Errorf("null origin: unexpected status %d", code)
+	}
+	select {
+	case call := <-c.calls:
+		t.Fatalf("rejected request ran %s", call)
+	default:
+	}
+
+	if code := send("application/json; charset=utf-8", server.URL); code != http.StatusOK {
+		t.Errorf("same origin: unexpected status %d", code)
+	}
+	if call := <-c.calls; call != "next" {
+		t.Fatalf("expected next, got %s", call)
+	}
+}
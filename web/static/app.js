@@ -0,0 +1,147 @@
+// dashboard mirroring the display, written for old browsers so no
+// modules, arrow functions or fetch
+(function () {
+  var state = null;
+  var received = 0; // time the state arrived, the seek advances from there
+  var volumeDragging = false;
+
+  function $(id) {
+    return document.getElementById(id);
+  }
+
+  function text(id, value) {
+    $(id).textContent = value || "";
+  }
+
+  function toggleClass(el, name, on) {
+    var classes = el.className.split(" ").filter(function (c) {
+      return c && c !== name;
+    });
+    if (on) {
+      classes.push(name);
+    }
+    el.className = classes.join(" ");
+  }
+
+  function clock(seconds) {
+    seconds = Math.max(0, Math.floor(seconds));
+    var s = seconds % 60;
+    var m = Math.floor(seconds / 60) % 60;
+    var h = Math.floor(seconds / 3600);
+    return (h > 0 ? h + ":" + (m < 10 ? "0" : "") : "") + m + ":" + (s < 10 ? "0" : "") + s;
+  }
+
+  function elapsed() {
+    var seek = state.seek;
+    if (state.status === "play") {
+      seek += Date.now() - received;
+    }
+    if (state.duration > 0) {
+      seek = Math.min(seek, state.duration * 1000);
+    }
+    return seek / 1000;
+  }
+
+  function progress() {
+    if (!state) {
+      return;
+    }
+    var seconds = elapsed();
+    text("seek", clock(seconds));
+    text("duration", state.duration > 0 ? "/ " + clock(state.duration) : "");
+    var percent = state.duration > 0 ? (100 * seconds) / state.duration : 0;
+    $("elapsed").style.width = percent + "%";
+  }
+
+  function details() {
+    var rows = [
+      state.trackType === "webradio" ? state.bitrate : state.bitdepth,
+      state.samplerate,
+      state.trackType,
+      state.service
+    ];
+    var list = $("details");
+    list.innerHTML = "";
+    rows.forEach(function (row) {
+      if (row) {
+        var li = document.createElement("li");
+        li.textContent = row;
+        list.appendChild(li);
+      }
+    });
+  }
+
+  function render(status) {
+    state = status;
+    received = Date.now();
+    text("conn", status.conn === "connected" ? "" : status.conn);
+    text("title", status.title);
+    text("album", status.album);
+    text("artist", status.artist);
+    $("toggle").innerHTML = status.status === "play" ? "&#9208;" : "&#9654;";
+    if (!volumeDragging) {
+      $("volume").value = status.volume;
+    }
+    toggleClass($("mute"), "on", status.mute);
+    toggleClass($("random"), "on", status.random);
+    toggleClass($("repeat"), "on", status.repeat || status.repeatSingle);
+    text("repeat", status.repeatSingle ? "repeat 1" : "repeat");
+    details();
+    progress();
+  }
+
+  function run(action) {
+    var xhr = new XMLHttpRequest();
+    xhr.open("POST", "api/action");
+    xhr.setRequestHeader("Content-Type", "application/json");
+    xhr.onload = function () {
+      var reply = {};
+      try {
+        reply = JSON.parse(xhr.responseText);
+      } catch (e) {}
+      text("error", reply.error);
+    };
+    xhr.onerror = function () {
+      text("error", "volumgui is not reachable");
+    };
+    xhr.send(JSON.stringify({ action: action }));
+  }
+
+  function connect() {
+    var events = new EventSource("api/events");
+    events.onmessage = function (e) {
+      render(JSON.parse(e.data));
+    };
+    events.onerror = function () {
+      text("conn", "reconnecting to volumgui");
+    };
+  }
+
+  var buttons = document.querySelectorAll("button[data-action]");
+  for (var i = 0; i < buttons.length; i++) {
+    buttons[i].onclick = function () {
+      run(this.getAttribute("data-action"));
+    };
+  }
+
+  var volume = $("volume");
+  volume.oninput = function () {
+    volumeDragging = true;
+  };
+  volume.onchange = function () {
+    volumeDragging = false;
+    run("volume " + volume.value);
+  };
+
+  $("bar").onclick = function (e) {
+    if (!state || !(state.duration > 0)) {
+      return;
+    }
+    var rect = this.getBoundingClientRect();
+    var fraction = (e.clientX - rect.left) / rect.width;
+    run("seek " + Math.round(fraction * state.duration));
+  };
+
+  setInterval(progress, 500);
+  connect();
+})();